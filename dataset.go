@@ -10,7 +10,10 @@
 package ddataset
 
 import (
+	"context"
 	"errors"
+	"fmt"
+
 	"github.com/lawrencewoodman/dlit"
 )
 
@@ -24,6 +27,57 @@ var ErrWrongNumFields = errors.New("wrong number of field names for dataset")
 // ErrReleased indicates that the dataset has been released
 var ErrReleased = errors.New("dataset has been released")
 
+// ErrNull is the error held by a Literal which represents a null value
+var ErrNull = errors.New("null")
+
+// RecordError describes a problem reading a record from a Dataset.  If
+// the record has the wrong number of fields then errors.Is reports it as
+// matching ErrWrongNumFields.
+type RecordError struct {
+	// Source is the name of where the record came from, such as a filename
+	Source string
+	// Record is the number of the record, counting from 1.  A header
+	// is record 0.
+	Record int64
+	// Line is the line the record starts on, counting from 1
+	Line int
+	// Column is the column where the problem was found, counting from 1,
+	// or 0 if it isn't known
+	Column int
+	// NumFields is the number of fields the record should have had
+	NumFields int
+	// NumValues is the number of fields the record had
+	NumValues int
+	// Err is the underlying error
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	msg := fmt.Sprintf("record %d, line %d", e.Record, e.Line)
+	if e.Column > 0 {
+		msg += fmt.Sprintf(", column %d", e.Column)
+	}
+	if e.Source != "" {
+		msg = e.Source + ": " + msg
+	}
+	msg += ": " + e.Err.Error()
+	if e.NumFields != e.NumValues {
+		msg += fmt.Sprintf(" (got %d, want %d)", e.NumValues, e.NumFields)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrWrongNumFields and the record had the
+// wrong number of fields
+func (e *RecordError) Is(target error) bool {
+	return target == ErrWrongNumFields && e.NumFields != e.NumValues
+}
+
 // Dataset provides access to a data source
 type Dataset interface {
 	// Open creates a connection to the Dataset
@@ -49,9 +103,80 @@ type Conn interface {
 	Close() error
 }
 
+// ContextOpener is implemented by Datasets which can create a connection
+// that is bound to a context.Context
+type ContextOpener interface {
+	// OpenContext creates a connection to the Dataset.  Once ctx is done
+	// Next on the connection returns false and Err returns ctx.Err()
+	OpenContext(ctx context.Context) (Conn, error)
+}
+
+// OpenContext creates a connection to Dataset d which is bound to ctx.
+// If d implements ContextOpener then its OpenContext method is used,
+// otherwise it falls back to d.Open and checks ctx on each call to Next.
+func OpenContext(ctx context.Context, d Dataset) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if co, ok := d.(ContextOpener); ok {
+		return co.OpenContext(ctx)
+	}
+	conn, err := d.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &contextConn{ctx: ctx, conn: conn, err: nil}, nil
+}
+
+// contextConn wraps a Conn so that it stops once ctx is done
+type contextConn struct {
+	ctx  context.Context
+	conn Conn
+	err  error
+}
+
+func (c *contextConn) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	if err := c.ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+	return c.conn.Next()
+}
+
+func (c *contextConn) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.conn.Err()
+}
+
+func (c *contextConn) Read() Record {
+	return c.conn.Read()
+}
+
+func (c *contextConn) Close() error {
+	return c.conn.Close()
+}
+
 // Record represents a single record/row from the Dataset
 type Record map[string]*dlit.Literal
 
+// NewNull returns a Literal which represents a null value.  The Literal
+// is an error Literal holding ErrNull, so that it can't be mistaken for
+// an empty string.
+func NewNull() *dlit.Literal {
+	return dlit.MustNew(ErrNull)
+}
+
+// IsNull returns whether l represents a null value.  A nil Literal,
+// such as that returned for a missing field, is also treated as null.
+func IsNull(l *dlit.Literal) bool {
+	return l == nil || l.Err() == ErrNull
+}
+
 // Clone creates a copy of the Record.  This is important where you might
 // want to store a record for later use or make use of two or more different
 // records at the same time.
@@ -1,6 +1,8 @@
 package ddataset
 
 import (
+	"context"
+	"errors"
 	"github.com/lawrencewoodman/dlit"
 	"testing"
 )
@@ -32,3 +34,158 @@ func TestRecordClone(t *testing.T) {
 		t.Errorf("records[1][\"age\"]: %s, want: 29", records[1]["age"])
 	}
 }
+
+func TestIsNull(t *testing.T) {
+	cases := []struct {
+		l    *dlit.Literal
+		want bool
+	}{
+		{NewNull(), true},
+		{nil, true},
+		{dlit.NewString(""), false},
+		{dlit.NewString("null"), false},
+		{dlit.MustNew(27), false},
+		{dlit.MustNew(errors.New("null")), false},
+	}
+	for i, c := range cases {
+		if got := IsNull(c.l); got != c.want {
+			t.Errorf("(%d) IsNull(%v) - got: %t, want: %t", i, c.l, got, c.want)
+		}
+	}
+}
+
+func TestRecordClone_null(t *testing.T) {
+	record := Record{"name": dlit.NewString("Mary Williams"), "dept": NewNull()}
+	got := record.Clone()
+	if !IsNull(got["dept"]) {
+		t.Errorf("Clone() - dept isn't null: %s", got["dept"])
+	}
+	if IsNull(got["name"]) {
+		t.Errorf("Clone() - name is null")
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	errQuote := errors.New("bare \" in non-quoted-field")
+	cases := []struct {
+		err           *RecordError
+		wantErr       string
+		wantNumFields bool
+	}{
+		{err: &RecordError{
+			Source:    "users.csv",
+			Record:    4,
+			Line:      5,
+			NumFields: 3,
+			NumValues: 2,
+			Err:       ErrWrongNumFields,
+		},
+			wantErr: "users.csv: record 4, line 5: " +
+				"wrong number of field names for dataset (got 2, want 3)",
+			wantNumFields: true,
+		},
+		{err: &RecordError{
+			Record: 7,
+			Line:   9,
+			Column: 12,
+			Err:    errQuote,
+		},
+			wantErr:       "record 7, line 9, column 12: bare \" in non-quoted-field",
+			wantNumFields: false,
+		},
+	}
+	for i, c := range cases {
+		if got := c.err.Error(); got != c.wantErr {
+			t.Errorf("(%d) Error - got: %s, want: %s", i, got, c.wantErr)
+		}
+		if got := errors.Is(c.err, ErrWrongNumFields); got != c.wantNumFields {
+			t.Errorf("(%d) errors.Is(ErrWrongNumFields) - got: %t, want: %t",
+				i, got, c.wantNumFields)
+		}
+		if !errors.Is(c.err, c.err.Err) {
+			t.Errorf("(%d) errors.Is(Err) - got: false, want: true", i)
+		}
+	}
+}
+
+func TestOpenContext(t *testing.T) {
+	ds := &sliceDataset{
+		records: []Record{
+			Record{"n": dlit.MustNew(1)},
+			Record{"n": dlit.MustNew(2)},
+			Record{"n": dlit.MustNew(3)},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := OpenContext(ctx, ds)
+	if err != nil {
+		t.Fatalf("OpenContext: %s", err)
+	}
+	defer conn.Close()
+	if !conn.Next() {
+		t.Fatalf("Next() - returned false on first record")
+	}
+	cancel()
+	if conn.Next() {
+		t.Errorf("Next() - returned true after context cancelled")
+	}
+	if err := conn.Err(); err != context.Canceled {
+		t.Errorf("Err() - got: %v, want: %s", err, context.Canceled)
+	}
+}
+
+func TestOpenContext_done(t *testing.T) {
+	ds := &sliceDataset{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := OpenContext(ctx, ds); err != context.Canceled {
+		t.Errorf("OpenContext - got err: %v, want: %s", err, context.Canceled)
+	}
+}
+
+// sliceDataset is a minimal Dataset which doesn't implement ContextOpener
+type sliceDataset struct {
+	records []Record
+}
+
+type sliceDatasetConn struct {
+	dataset   *sliceDataset
+	recordNum int
+}
+
+func (d *sliceDataset) Open() (Conn, error) {
+	return &sliceDatasetConn{dataset: d, recordNum: -1}, nil
+}
+
+func (d *sliceDataset) Fields() []string {
+	return []string{"n"}
+}
+
+func (d *sliceDataset) NumRecords() int64 {
+	return int64(len(d.records))
+}
+
+func (d *sliceDataset) Release() error {
+	return nil
+}
+
+func (c *sliceDatasetConn) Next() bool {
+	if c.recordNum+1 < len(c.dataset.records) {
+		c.recordNum++
+		return true
+	}
+	return false
+}
+
+func (c *sliceDatasetConn) Err() error {
+	return nil
+}
+
+func (c *sliceDatasetConn) Read() Record {
+	return c.dataset.records[c.recordNum]
+}
+
+func (c *sliceDatasetConn) Close() error {
+	return nil
+}
@@ -10,6 +10,8 @@
 package dcache
 
 import (
+	"context"
+
 	"github.com/lawrencewoodman/ddataset"
 	"github.com/lawrencewoodman/ddataset/internal"
 )
@@ -26,6 +28,7 @@ type DCache struct {
 
 // DCacheConn represents a connection to a DCache Dataset
 type DCacheConn struct {
+	ctx       context.Context
 	dataset   *DCache
 	conn      ddataset.Conn
 	recordNum int64
@@ -76,22 +79,30 @@ func New(
 
 // Open creates a connection to the Dataset
 func (c *DCache) Open() (ddataset.Conn, error) {
+	return c.OpenContext(context.Background())
+}
+
+// OpenContext creates a connection to the Dataset which stops returning
+// Records once ctx is done
+func (c *DCache) OpenContext(ctx context.Context) (ddataset.Conn, error) {
 	if c.isReleased {
 		return nil, ddataset.ErrReleased
 	}
 	if c.allCached {
 		return &DCacheConn{
+			ctx:       ctx,
 			dataset:   c,
 			conn:      nil,
 			recordNum: -1,
 			err:       nil,
 		}, nil
 	}
-	conn, err := c.dataset.Open()
+	conn, err := ddataset.OpenContext(ctx, c.dataset)
 	if err != nil {
 		return nil, err
 	}
 	return &DCacheConn{
+		ctx:       ctx,
 		dataset:   c,
 		conn:      conn,
 		recordNum: -1,
@@ -107,6 +118,14 @@ func (c *DCache) Fields() []string {
 	return c.dataset.Fields()
 }
 
+// Schema returns the Schema of the underlying Dataset
+func (c *DCache) Schema() ddataset.Schema {
+	if c.isReleased {
+		return ddataset.Schema{}
+	}
+	return ddataset.SchemaOf(c.dataset)
+}
+
 // NumRecords returns the number of records in the Dataset.  If there is
 // a problem getting the number of records it returns -1.  NOTE: The returned
 // value can change if the underlying Dataset changes.
@@ -132,6 +151,13 @@ func (d *DCache) Release() error {
 
 // Next returns whether there is a Record to be Read
 func (cc *DCacheConn) Next() bool {
+	if cc.err != nil {
+		return false
+	}
+	if err := cc.ctx.Err(); err != nil {
+		cc.err = err
+		return false
+	}
 	if cc.dataset.allCached {
 		if (cc.recordNum + 1) < cc.dataset.cachedRows {
 			cc.recordNum++
@@ -153,6 +179,9 @@ func (cc *DCacheConn) Next() bool {
 
 // Err returns any errors from the connection
 func (cc *DCacheConn) Err() error {
+	if cc.err != nil {
+		return cc.err
+	}
 	if cc.dataset.allCached {
 		return nil
 	}
@@ -2,7 +2,6 @@ package dcache
 
 import (
 	"encoding/csv"
-	"errors"
 	"fmt"
 	"github.com/lawrencewoodman/ddataset"
 	"github.com/lawrencewoodman/ddataset/dcsv"
@@ -79,10 +78,13 @@ func TestNew_errors(t *testing.T) {
 			separator:    ',',
 			fieldNames:   []string{"band", "score", "team", "points", "rating"},
 			maxCacheRows: 105,
-			wantErr: &csv.ParseError{
-				Line:   102,
-				Column: 0,
-				Err:    csv.ErrFieldCount,
+			wantErr: &ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "invalid_numfields_at_102.csv"),
+				Record:    102,
+				Line:      102,
+				NumFields: 5,
+				NumValues: 4,
+				Err:       csv.ErrFieldCount,
 			}},
 		{filename: "missing.csv",
 			separator:    ',',
@@ -97,7 +99,14 @@ func TestNew_errors(t *testing.T) {
 				"balance", "housing", "loan", "contact", "day", "month", "duration",
 				"campaign", "pdays", "previous", "poutcome"},
 			maxCacheRows: 4,
-			wantErr:      errors.New("wrong number of field names for dataset"),
+			wantErr: &ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "bank.csv"),
+				Record:    1,
+				Line:      1,
+				NumFields: 16,
+				NumValues: 17,
+				Err:       ddataset.ErrWrongNumFields,
+			},
 		},
 	}
 
@@ -295,6 +304,28 @@ func TestFields(t *testing.T) {
 	}
 }
 
+func TestSchema(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	schema := ddataset.Schema{
+		{Name: "name", Kind: ddataset.KindString},
+		{Name: "balance", Kind: ddataset.KindInt},
+		{Name: "numCards", Kind: ddataset.KindInt},
+		{Name: "martialStatus", Kind: ddataset.KindString},
+		{Name: "tertiaryEducated", Kind: ddataset.KindBool, Nullable: true},
+		{Name: "success", Kind: ddataset.KindBool},
+	}
+	for _, maxCacheRows := range []int64{0, 10000} {
+		ds := dcsv.NewWithSchema(filename, true, ',', schema)
+		cds, err := New(ds, maxCacheRows)
+		if err != nil {
+			t.Fatalf("New: %s", err)
+		}
+		if got := ddataset.SchemaOf(cds); !reflect.DeepEqual(got, schema) {
+			t.Errorf("Schema() - got: %v, want: %v", got, schema)
+		}
+	}
+}
+
 func TestNumRecords(t *testing.T) {
 	cases := []struct {
 		filename     string
@@ -373,10 +404,13 @@ func TestErr(t *testing.T) {
 		{filepath.Join("fixtures", "invalid_numfields_at_102.csv"), ',',
 			[]string{"band", "score", "team", "points", "rating"},
 			100,
-			&csv.ParseError{
-				Line:   102,
-				Column: 0,
-				Err:    csv.ErrFieldCount,
+			&ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "invalid_numfields_at_102.csv"),
+				Record:    102,
+				Line:      102,
+				NumFields: 5,
+				NumValues: 4,
+				Err:       csv.ErrFieldCount,
 			}},
 		{filepath.Join("fixtures", "bank.csv"), ';',
 			[]string{"age", "job", "marital", "education", "default", "balance",
@@ -411,6 +445,28 @@ func TestErr(t *testing.T) {
 	}
 }
 
+func TestRead_nulls(t *testing.T) {
+	filename := filepath.Join("fixtures", "nulls.csv")
+	schema := ddataset.NewSchema([]string{"name", "dept", "age"})
+	for _, maxCacheRows := range []int64{0, 2, 100} {
+		ds := dcsv.NewWithOptions(
+			filename,
+			true,
+			',',
+			schema,
+			dcsv.Options{NullToken: "NA"},
+		)
+		cds, err := New(ds, maxCacheRows)
+		if err != nil {
+			t.Fatalf("New: %s", err)
+		}
+		if err := testhelpers.CheckDatasetsEqual(ds, cds); err != nil {
+			t.Errorf("(maxCacheRows: %d) CheckDatasetsEqual: %s",
+				maxCacheRows, err)
+		}
+	}
+}
+
 func TestNext(t *testing.T) {
 	cases := []struct {
 		filename       string
@@ -552,6 +608,25 @@ func TestOpenNextRead_goroutines(t *testing.T) {
 	}
 }
 
+func TestOpenContext_cancel(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	fieldNames := []string{
+		"name", "balance", "numCards", "martialStatus",
+		"tertiaryEducated", "success",
+	}
+	for _, maxCacheRows := range []int64{0, 100, 10000} {
+		ds := dcsv.New(filename, true, ',', fieldNames)
+		cds, err := New(ds, maxCacheRows)
+		if err != nil {
+			t.Fatalf("New: %s", err)
+		}
+		if err := testhelpers.CheckOpenContextCancel(cds, 5); err != nil {
+			t.Errorf("(maxCacheRows: %d) CheckOpenContextCancel: %s",
+				maxCacheRows, err)
+		}
+	}
+}
+
 /*************************
  *  Benchmarks
  *************************/
@@ -13,6 +13,7 @@
 package dcopy
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io/ioutil"
@@ -21,8 +22,13 @@ import (
 
 	"github.com/lawrencewoodman/ddataset"
 	"github.com/lawrencewoodman/ddataset/dcsv"
+	"github.com/lawrencewoodman/dlit"
 )
 
+// nullToken is used to represent null values in the CSV copy.  Values
+// which would be confused with it are escaped using escapeValue.
+const nullToken = `\N`
+
 // DCopy represents a copy of a Dataset
 type DCopy struct {
 	dataset    ddataset.Dataset
@@ -68,7 +74,11 @@ func New(dataset ddataset.Dataset, tmpDir string) (ddataset.Dataset, error) {
 		numRecords++
 		record := conn.Read()
 		for i, f := range dataset.Fields() {
-			strRecord[i] = record[f].String()
+			if ddataset.IsNull(record[f]) {
+				strRecord[i] = nullToken
+			} else {
+				strRecord[i] = escapeValue(record[f].String())
+			}
 		}
 		if err := w.Write(strRecord); err != nil {
 			os.RemoveAll(tmpDir)
@@ -88,7 +98,13 @@ func New(dataset ddataset.Dataset, tmpDir string) (ddataset.Dataset, error) {
 	}
 
 	return &DCopy{
-		dataset:    dcsv.New(copyFilename, false, ',', dataset.Fields()),
+		dataset: dcsv.NewWithOptions(
+			copyFilename,
+			false,
+			',',
+			ddataset.SchemaOf(dataset),
+			dcsv.Options{NullToken: nullToken},
+		),
 		tmpDir:     tmpDir,
 		isReleased: false,
 		numRecords: numRecords,
@@ -97,10 +113,16 @@ func New(dataset ddataset.Dataset, tmpDir string) (ddataset.Dataset, error) {
 
 // Open creates a connection to the Dataset
 func (d *DCopy) Open() (ddataset.Conn, error) {
+	return d.OpenContext(context.Background())
+}
+
+// OpenContext creates a connection to the Dataset which stops returning
+// Records once ctx is done
+func (d *DCopy) OpenContext(ctx context.Context) (ddataset.Conn, error) {
 	if d.isReleased {
 		return nil, ddataset.ErrReleased
 	}
-	conn, err := d.dataset.Open()
+	conn, err := ddataset.OpenContext(ctx, d.dataset)
 	if err != nil {
 		return nil, err
 	}
@@ -118,6 +140,14 @@ func (d *DCopy) Fields() []string {
 	return d.dataset.Fields()
 }
 
+// Schema returns the Schema of the Dataset that was copied
+func (d *DCopy) Schema() ddataset.Schema {
+	if d.isReleased {
+		return ddataset.Schema{}
+	}
+	return ddataset.SchemaOf(d.dataset)
+}
+
 // NumRecords returns the number of records in the Dataset.
 func (d *DCopy) NumRecords() int64 {
 	return d.numRecords
@@ -139,7 +169,19 @@ func (d *DCopy) Release() error {
 
 // Next returns whether there is a Record to be Read
 func (c *DCopyConn) Next() bool {
-	return c.conn.Next()
+	if !c.conn.Next() {
+		return false
+	}
+	record := c.conn.Read()
+	for f, l := range record {
+		if ddataset.IsNull(l) {
+			continue
+		}
+		if s := l.String(); isEscaped(s) {
+			record[f] = dlit.NewString(s[1:])
+		}
+	}
+	return true
 }
 
 // Err returns any errors from the connection
@@ -156,3 +198,31 @@ func (c *DCopyConn) Read() ddataset.Record {
 func (c *DCopyConn) Close() error {
 	return c.conn.Close()
 }
+
+// escapeValue escapes s if it is nullToken or looks like an escaped
+// nullToken, by adding a backslash to the start, so that it isn't read
+// as null from the CSV copy
+func escapeValue(s string) string {
+	if isNullLike(s) {
+		return `\` + s
+	}
+	return s
+}
+
+// isEscaped returns whether s was escaped by escapeValue
+func isEscaped(s string) bool {
+	return len(s) > len(nullToken) && isNullLike(s)
+}
+
+// isNullLike returns whether s is one or more backslashes followed by N
+func isNullLike(s string) bool {
+	if len(s) < 2 || s[len(s)-1] != 'N' {
+		return false
+	}
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] != '\\' {
+			return false
+		}
+	}
+	return true
+}
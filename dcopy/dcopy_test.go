@@ -2,7 +2,6 @@ package dcopy
 
 import (
 	"encoding/csv"
-	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -15,6 +14,7 @@ import (
 	"github.com/lawrencewoodman/ddataset"
 	"github.com/lawrencewoodman/ddataset/dcsv"
 	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
 )
 
 func TestNew(t *testing.T) {
@@ -32,6 +32,11 @@ func TestNew(t *testing.T) {
 			[]string{"name", "balance", "numCards", "martialStatus",
 				"tertiaryEducated", "success"},
 		},
+		{filepath.Join("fixtures", "bank.csv.gz"), ';',
+			[]string{"age", "job", "marital", "education", "default", "balance",
+				"housing", "loan", "contact", "day", "month", "duration", "campaign",
+				"pdays", "previous", "poutcome", "y"},
+		},
 	}
 
 	for _, c := range cases {
@@ -105,10 +110,13 @@ func TestNew_errors(t *testing.T) {
 		{filename: filepath.Join("fixtures", "invalid_numfields_at_102.csv"),
 			separator:  ',',
 			fieldNames: []string{"band", "score", "team", "points", "rating"},
-			wantErr: &csv.ParseError{
-				Line:   102,
-				Column: 0,
-				Err:    csv.ErrFieldCount,
+			wantErr: &ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "invalid_numfields_at_102.csv"),
+				Record:    102,
+				Line:      102,
+				NumFields: 5,
+				NumValues: 4,
+				Err:       csv.ErrFieldCount,
 			},
 		},
 		{filename: "missing.csv",
@@ -121,7 +129,14 @@ func TestNew_errors(t *testing.T) {
 			fieldNames: []string{"age", "job", "marital", "education", "default",
 				"balance", "housing", "loan", "contact", "day", "month", "duration",
 				"campaign", "pdays", "previous", "poutcome"},
-			wantErr: errors.New("wrong number of field names for dataset"),
+			wantErr: &ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "bank.csv"),
+				Record:    1,
+				Line:      1,
+				NumFields: 16,
+				NumValues: 17,
+				Err:       ddataset.ErrWrongNumFields,
+			},
 		},
 	}
 	for _, c := range cases {
@@ -268,6 +283,27 @@ func TestFields(t *testing.T) {
 	}
 }
 
+func TestSchema(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	schema := ddataset.Schema{
+		{Name: "name", Kind: ddataset.KindString},
+		{Name: "balance", Kind: ddataset.KindInt},
+		{Name: "numCards", Kind: ddataset.KindInt},
+		{Name: "martialStatus", Kind: ddataset.KindString},
+		{Name: "tertiaryEducated", Kind: ddataset.KindBool, Nullable: true},
+		{Name: "success", Kind: ddataset.KindBool},
+	}
+	ds := dcsv.NewWithSchema(filename, true, ',', schema)
+	cds, err := New(ds, "")
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	defer cds.Release()
+	if got := ddataset.SchemaOf(cds); !reflect.DeepEqual(got, schema) {
+		t.Errorf("Schema() - got: %v, want: %v", got, schema)
+	}
+}
+
 func TestNumRecords(t *testing.T) {
 	cases := []struct {
 		filename   string
@@ -335,6 +371,113 @@ func TestRelease_error(t *testing.T) {
 	}
 }
 
+func TestRead_nulls(t *testing.T) {
+	filename := filepath.Join("fixtures", "nulls.csv")
+	schema := ddataset.NewSchema([]string{"name", "dept", "age"})
+	wantRecords := []ddataset.Record{
+		ddataset.Record{
+			"name": dlit.MustNew("Fred Wilkins"),
+			"dept": dlit.MustNew("Logistics"),
+			"age":  dlit.MustNew(35),
+		},
+		ddataset.Record{
+			"name": dlit.MustNew("George Eliot"),
+			"dept": ddataset.NewNull(),
+			"age":  dlit.MustNew(""),
+		},
+		ddataset.Record{
+			"name": dlit.MustNew("Mary Terence"),
+			"dept": dlit.MustNew(""),
+			"age":  ddataset.NewNull(),
+		},
+		ddataset.Record{
+			"name": dlit.MustNew("Ned James"),
+			"dept": dlit.MustNew("Shipping"),
+			"age":  dlit.MustNew(41),
+		},
+	}
+	ds := dcsv.NewWithOptions(
+		filename,
+		true,
+		',',
+		schema,
+		dcsv.Options{NullToken: "NA"},
+	)
+	cds, err := New(ds, "")
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	defer cds.Release()
+	conn, err := cds.Open()
+	if err != nil {
+		t.Fatalf("Open() err: %s", err)
+	}
+	defer conn.Close()
+	for _, wantRecord := range wantRecords {
+		if !conn.Next() {
+			t.Fatalf("Next() - return false early")
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, wantRecord) {
+			t.Errorf("Read() got: %s, want: %s", record, wantRecord)
+		}
+	}
+	if conn.Next() {
+		t.Errorf("Next() - return true, despite having finished")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err() err: %s", err)
+	}
+}
+
+func TestRead_nullLike(t *testing.T) {
+	filename := filepath.Join("fixtures", "backslashes.csv")
+	schema := ddataset.NewSchema([]string{"name", "note"})
+	noteRecord := func(name string, note *dlit.Literal) ddataset.Record {
+		return ddataset.Record{"name": dlit.NewString(name), "note": note}
+	}
+	wantRecords := []ddataset.Record{
+		noteRecord("Fred", dlit.NewString(`\N`)),
+		noteRecord("George", dlit.NewString(`\\N`)),
+		noteRecord("Mary", ddataset.NewNull()),
+		noteRecord("Ned", dlit.NewString("N")),
+		noteRecord("Ann", dlit.NewString(`\`)),
+		noteRecord("Bob", dlit.NewString(`\\\N`)),
+	}
+	ds := dcsv.NewWithOptions(
+		filename,
+		true,
+		',',
+		schema,
+		dcsv.Options{NullToken: "NA"},
+	)
+	cds, err := New(ds, "")
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	defer cds.Release()
+	conn, err := cds.Open()
+	if err != nil {
+		t.Fatalf("Open() err: %s", err)
+	}
+	defer conn.Close()
+	for _, wantRecord := range wantRecords {
+		if !conn.Next() {
+			t.Fatalf("Next() - return false early")
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, wantRecord) {
+			t.Errorf("Read() got: %s, want: %s", record, wantRecord)
+		}
+	}
+	if conn.Next() {
+		t.Errorf("Next() - return true, despite having finished")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err() err: %s", err)
+	}
+}
+
 func TestNext(t *testing.T) {
 	cases := []struct {
 		filename       string
@@ -469,6 +612,23 @@ func TestOpenNextRead_goroutines(t *testing.T) {
 	}
 }
 
+func TestOpenContext_cancel(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	fieldNames := []string{
+		"name", "balance", "numCards", "martialStatus",
+		"tertiaryEducated", "success",
+	}
+	ds := dcsv.New(filename, true, ',', fieldNames)
+	cds, err := New(ds, "")
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	defer cds.Release()
+	if err := testhelpers.CheckOpenContextCancel(cds, 5); err != nil {
+		t.Errorf("CheckOpenContextCancel: %s", err)
+	}
+}
+
 /*************************
  *  Benchmarks
  *************************/
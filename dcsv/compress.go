@@ -0,0 +1,81 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	// bzip2BlockMagic and bzip2EndMagic follow the bzip2 header and
+	// are checked to avoid mistaking a text file starting "BZh" for
+	// a bzip2 file
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+// decompress returns a reader which decompresses r if filename has a
+// .gz or .bz2 extension or r starts with the magic bytes of a gzip or
+// bzip2 file.  Otherwise the returned reader reads r unchanged.
+func decompress(filename string, r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gz":
+		return gzip.NewReader(br)
+	case ".bz2":
+		return bzip2.NewReader(br), nil
+	}
+	switch {
+	case isGzip(br):
+		return gzip.NewReader(br)
+	case isBzip2(br):
+		return bzip2.NewReader(br), nil
+	}
+	return br, nil
+}
+
+// isCompressed returns whether decompress would decompress br
+func isCompressed(filename string, br *bufio.Reader) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gz", ".bz2":
+		return true
+	}
+	return isGzip(br) || isBzip2(br)
+}
+
+// isGzip returns whether br starts with the magic bytes of a gzip file
+func isGzip(br *bufio.Reader) bool {
+	magic, _ := br.Peek(len(gzipMagic))
+	return bytes.Equal(magic, gzipMagic)
+}
+
+// isBzip2 returns whether br starts with the magic bytes of a bzip2 file.
+// More bytes are only peeked while they match, so that a stream which
+// has fewer bytes available isn't waited on unless it looks like bzip2.
+func isBzip2(br *bufio.Reader) bool {
+	magic, _ := br.Peek(2)
+	if len(magic) < 2 || !bytes.HasPrefix(bzip2Magic, magic) {
+		return false
+	}
+	magic, _ = br.Peek(len(bzip2Magic) + 1)
+	if len(magic) < len(bzip2Magic)+1 ||
+		!bytes.HasPrefix(magic, bzip2Magic) ||
+		magic[3] < '1' || magic[3] > '9' {
+		return false
+	}
+	magic, _ = br.Peek(len(bzip2Magic) + 1 + len(bzip2BlockMagic))
+	if len(magic) < len(bzip2Magic)+1+len(bzip2BlockMagic) {
+		return false
+	}
+	return bytes.Equal(magic[4:], bzip2BlockMagic) ||
+		bytes.Equal(magic[4:], bzip2EndMagic)
+}
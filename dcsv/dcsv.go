@@ -6,36 +6,156 @@
  * Licensed under an MIT licence.  Please see LICENCE.md for details.
  */
 
-// Package dcsv handles access to a CSV file as Dataset
+// Package dcsv handles access to a CSV file as Dataset.  Files which are
+// compressed with gzip or bzip2 are decompressed transparently and files
+// which aren't UTF-8 can be transcoded using Options.Encoding.  A
+// Dataset can also be made from several CSV files using NewMulti or NewGlob,
+// from a file in an fs.FS using NewFromFS or from any stream using
+// NewFromReader.
 package dcsv
 
 import (
+	"bufio"
+	"context"
 	"encoding/csv"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/lawrencewoodman/ddataset"
-	"github.com/lawrencewoodman/ddataset/internal"
 	"github.com/lawrencewoodman/dlit"
 )
 
 // DCSV represents a CSV file Dataset
 type DCSV struct {
-	filename   string
+	filename string
+	// open returns a new stream of the file for each connection
+	open func() (io.ReadCloser, error)
+	// stat returns information about the file or is nil if there
+	// isn't any
+	stat       func() (fs.FileInfo, error)
 	fieldNames []string
+	schema     ddataset.Schema
 	hasHeader  bool
 	separator  rune
 	numFields  int
+	options    Options
 	isReleased bool
+	index      *index
+	indexMu    sync.Mutex
+
+	// fields is the names of the fields returned by Fields
+	fields []string
+	// projection is the Schema of the fields returned by Fields
+	projection ddataset.Schema
+	// fieldIndexes is the index in fieldNames of each field returned by
+	// Fields or nil if all the fields are returned
+	fieldIndexes []int
+	// projectionErr is any error with Options.Fields
+	projectionErr error
 }
 
 // DCSVConn represents a connection to a DCSV Dataset
 type DCSVConn struct {
+	ctx           context.Context
 	dataset       *DCSV
-	file          *os.File
+	file          io.ReadCloser
 	reader        *csv.Reader
+	recorder      *rowRecorder
+	chunks        *chunkReader
+	rejects       *os.File
+	ownsRejects   bool
+	onMalformed   func(err *ddataset.RecordError, raw string)
+	fieldColumns  []int
 	currentRecord ddataset.Record
-	err           error
+	recordNum     int64
+	numSkipped    int64
+	// lineOffset is the number of lines before the csv.Reader's input
+	// after SeekRecord
+	lineOffset int
+	err        error
+}
+
+// Options configures a DCSV Dataset created with NewWithOptions
+type Options struct {
+	// LazyQuotes is passed to csv.Reader.  It allows a quote to appear
+	// in an unquoted field and a non-doubled quote in a quoted field.
+	LazyQuotes bool
+	// Comment is passed to csv.Reader.  If it isn't 0 then lines
+	// beginning with it are ignored.
+	Comment rune
+	// TrimLeadingSpace is passed to csv.Reader.  It makes leading white
+	// space in a field be ignored.
+	TrimLeadingSpace bool
+	// ReuseRecord is passed to csv.Reader.  It lets the csv.Reader reuse
+	// the slice it returns for each row, which reduces allocations.
+	ReuseRecord bool
+	// SkipLines is the number of lines to skip at the start of the file,
+	// before any header.  Line numbers in errors still count these lines.
+	SkipLines int
+	// NullToken is a value which is read as a null value.  If it is
+	// the empty string then no values are read as null.
+	NullToken string
+	// HeaderMatch determines how the header is checked against the field
+	// names each time a connection is opened.  If the header doesn't
+	// match then Open returns an error.
+	HeaderMatch HeaderMatch
+	// SkipMalformed makes rows which can't be parsed or which have the
+	// wrong number of fields be skipped, rather than stopping the
+	// connection with an error
+	SkipMalformed bool
+	// OnMalformed is called, if not nil, for each row skipped because of
+	// SkipMalformed with the error describing the problem and the raw text
+	// of the row.  It is called for every connection opened with Open or
+	// OpenContext, including those opened by other Datasets wrapping this
+	// one, but not for those used internally by NumRecords.
+	OnMalformed func(err *ddataset.RecordError, raw string)
+	// RejectsFilename is the name of a file to write the raw text of
+	// each row skipped because of SkipMalformed.  If it is empty then
+	// skipped rows aren't written anywhere.  The file is created or
+	// truncated each time a connection is opened with Open or OpenContext,
+	// so it only holds the rows skipped by the latest connection.  This
+	// includes connections opened by other Datasets wrapping this one,
+	// such as when a dcache Dataset reads past its cache.  Connections
+	// open at the same time overwrite each other's rows, so they should
+	// use Datasets with different RejectsFilenames.  Connections used
+	// internally by NumRecords don't write to the file.
+	RejectsFilename string
+	// Workers is the number of goroutines used to parse the file.  If it
+	// is greater than 1 the file is split into chunks which end at record
+	// boundaries and the chunks are parsed in parallel.  Records are still
+	// returned in the order they appear in the file.  This only helps if
+	// more than one CPU is available.  Otherwise the file is parsed by the
+	// goroutine calling Next.
+	Workers int
+	// ChunkSize is the approximate size in bytes of the chunks the file
+	// is split into when Workers is greater than 1.  If it is less than 1
+	// then a size of 1MiB is used.
+	ChunkSize int
+	// IndexFilename is the name of a file used to store an index of the
+	// byte offset of every IndexInterval'th record.  If it is set then
+	// NumRecords uses the index and connections can use SeekRecord.  The
+	// index is built when first needed and rebuilt if the size or
+	// modification time of the CSV file changes.  Compressed files and
+	// files which aren't UTF-8 can't be indexed.
+	IndexFilename string
+	// IndexInterval is the number of records between each offset stored
+	// in the index.  If it is less than 1 then 1000 is used.
+	IndexInterval int
+	// Encoding is the character encoding of the file, which is transcoded
+	// to UTF-8 as it is read.  A byte order mark at the start of the file
+	// is removed and takes precedence over Encoding.
+	Encoding Encoding
+	// Fields is the names of the fields to read, in the order they are
+	// returned by Fields, which must be a subset of the field names of the
+	// Dataset.  Only these fields are put in each Record, which reduces
+	// allocations when a file has many more fields than are needed.  If it
+	// is empty then all the fields are read.  Every field is still used to
+	// check the header and number of fields of each row.
+	Fields []string
 }
 
 // New creates a new DCSV Dataset
@@ -45,46 +165,239 @@ func New(
 	separator rune,
 	fieldNames []string,
 ) ddataset.Dataset {
+	return newDCSV(
+		filename,
+		hasHeader,
+		separator,
+		fieldNames,
+		ddataset.NewSchema(fieldNames),
+		Options{},
+	)
+}
+
+// NewWithSchema creates a new DCSV Dataset whose fields are described
+// by schema
+func NewWithSchema(
+	filename string,
+	hasHeader bool,
+	separator rune,
+	schema ddataset.Schema,
+) ddataset.Dataset {
+	return newDCSV(
+		filename,
+		hasHeader,
+		separator,
+		schema.Names(),
+		schema,
+		Options{},
+	)
+}
+
+// NewWithOptions creates a new DCSV Dataset whose fields are described
+// by schema and which is configured by options
+func NewWithOptions(
+	filename string,
+	hasHeader bool,
+	separator rune,
+	schema ddataset.Schema,
+	options Options,
+) ddataset.Dataset {
+	return newDCSV(
+		filename,
+		hasHeader,
+		separator,
+		schema.Names(),
+		schema,
+		options,
+	)
+}
+
+// NewFromHeader creates a new DCSV Dataset from a file with a header
+// whose field names are taken from the header.  The header is read when
+// the Dataset is created, so an error is returned if the file can't be
+// read or the header has a duplicate field name.
+func NewFromHeader(
+	filename string,
+	separator rune,
+	options Options,
+) (ddataset.Dataset, error) {
+	d := newDCSV(filename, true, separator, []string{}, ddataset.Schema{}, options)
+	f, _, _, header, err := d.makeCsvReader(false)
+	if err != nil {
+		return nil, d.recordError(0, nil, err)
+	}
+	f.Close()
+	if err := checkHeaderUnique(filename, header); err != nil {
+		return nil, err
+	}
+	d = newDCSV(
+		filename,
+		true,
+		separator,
+		header,
+		ddataset.NewSchema(header),
+		options,
+	)
+	if d.projectionErr != nil {
+		return nil, d.projectionErr
+	}
+	return d, nil
+}
+
+func newDCSV(
+	filename string,
+	hasHeader bool,
+	separator rune,
+	fieldNames []string,
+	schema ddataset.Schema,
+	options Options,
+) *DCSV {
+	projection, fieldIndexes, projectionErr :=
+		projectSchema(schema, options.Fields)
+	fields := fieldNames
+	if len(options.Fields) > 0 {
+		fields = projection.Names()
+	}
 	return &DCSV{
-		filename:   filename,
-		fieldNames: fieldNames,
-		hasHeader:  hasHeader,
-		separator:  separator,
-		numFields:  len(fieldNames),
-		isReleased: false,
+		filename: filename,
+		open: func() (io.ReadCloser, error) {
+			return os.Open(filename)
+		},
+		stat: func() (fs.FileInfo, error) {
+			return os.Stat(filename)
+		},
+		fieldNames:    fieldNames,
+		schema:        schema,
+		fields:        fields,
+		projection:    projection,
+		fieldIndexes:  fieldIndexes,
+		projectionErr: projectionErr,
+		hasHeader:     hasHeader,
+		separator:     separator,
+		numFields:     len(fieldNames),
+		options:       options,
+		isReleased:    false,
 	}
 }
 
 // Open creates a connection to the Dataset
 func (d *DCSV) Open() (ddataset.Conn, error) {
+	return d.OpenContext(context.Background())
+}
+
+// OpenContext creates a connection to the Dataset which stops
+// returning Records once ctx is done
+func (d *DCSV) OpenContext(ctx context.Context) (ddataset.Conn, error) {
 	if d.isReleased {
 		return nil, ddataset.ErrReleased
 	}
-	f, r, err := makeCsvReader(d.filename, d.separator, d.hasHeader)
+	conn, err := d.openConn(ctx, d.recordsRows())
 	if err != nil {
 		return nil, err
 	}
-	r.Comma = d.separator
+	if d.options.SkipMalformed && d.options.RejectsFilename != "" {
+		rejects, err := os.Create(d.options.RejectsFilename)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.rejects = rejects
+		conn.ownsRejects = true
+	}
+	return conn, nil
+}
 
+// openConn creates a connection to the Dataset without a rejects file.
+// If recordRows is set then the connection has a rowRecorder.
+func (d *DCSV) openConn(
+	ctx context.Context,
+	recordRows bool,
+) (*DCSVConn, error) {
+	if d.projectionErr != nil {
+		return nil, d.projectionErr
+	}
+	f, r, recorder, header, err := d.makeCsvReader(recordRows)
+	if err != nil {
+		return nil, d.recordError(0, nil, err)
+	}
+	fieldColumns, err := d.matchHeader(header)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var chunks *chunkReader
+	if d.isParallel() {
+		first, rest := d.chunkFieldsPerRecord(header)
+		chunks = newChunkReader(d, recorder.rest(), recorder.numLines+1, first, rest)
+		recorder = nil
+	}
 	return &DCSVConn{
+		ctx:           ctx,
 		dataset:       d,
 		file:          f,
 		reader:        r,
-		currentRecord: make(ddataset.Record, d.numFields),
+		recorder:      recorder,
+		chunks:        chunks,
+		rejects:       nil,
+		ownsRejects:   false,
+		onMalformed:   d.options.OnMalformed,
+		fieldColumns:  d.projectColumns(fieldColumns),
+		currentRecord: make(ddataset.Record, len(d.fields)),
+		recordNum:     0,
+		numSkipped:    0,
+		lineOffset:    0,
 		err:           nil,
 	}, nil
 }
 
-// Fields returns the field names used by the Dataset
+// Fields returns the field names used by the Dataset.  If Options.Fields
+// is set then these are the fields it names.
 func (d *DCSV) Fields() []string {
-	return d.fieldNames
+	return d.fields
+}
+
+// Schema returns the Schema of the Dataset.  If Options.Fields is set
+// then it only describes the fields it names.
+func (d *DCSV) Schema() ddataset.Schema {
+	return d.projection
 }
 
-// NumRecords returns the number of records in the Dataset.  If there is
-// a problem getting the number of records it returns -1.  NOTE: The returned
-// value can change if the underlying Dataset changes.
+// NumRecords returns the number of records in the Dataset.  If
+// Options.IndexFilename is set then the number is taken from the index.
+// If there is a problem getting the number of records it returns -1.
+// NOTE: The returned value can change if the underlying Dataset changes.
 func (d *DCSV) NumRecords() int64 {
-	return internal.CountNumRecords(d)
+	if d.isReleased {
+		return -1
+	}
+	if d.options.IndexFilename == "" {
+		return d.countRecords()
+	}
+	idx, err := d.loadIndex()
+	if err != nil {
+		return -1
+	}
+	return idx.NumRecords
+}
+
+// countRecords returns the number of records in the Dataset, or -1 if
+// there is a problem, using a connection which doesn't write to the
+// rejects file or call Options.OnMalformed
+func (d *DCSV) countRecords() int64 {
+	c, err := d.openConn(context.Background(), d.recordsRows())
+	if err != nil {
+		return -1
+	}
+	defer c.Close()
+	c.onMalformed = nil
+	numRecords := int64(0)
+	for c.Next() {
+		numRecords++
+	}
+	if c.Err() != nil {
+		return -1
+	}
+	return numRecords
 }
 
 // Release releases any resources associated with the Dataset d,
@@ -106,20 +419,46 @@ func (c *DCSVConn) Next() bool {
 		c.err = ddataset.ErrConnClosed
 		return false
 	}
-	row, err := c.reader.Read()
-	if err == io.EOF {
-		return false
-	} else if err != nil {
-		c.Close()
-		c.err = err
-		return false
-	}
-	if err := c.makeRowCurrentRecord(row); err != nil {
-		c.Close()
-		c.err = err
-		return false
+	for {
+		if err := c.ctx.Err(); err != nil {
+			c.Close()
+			c.err = err
+			return false
+		}
+		row, err := c.readRow()
+		if err == io.EOF {
+			return false
+		}
+		c.recordNum++
+		if err != nil {
+			err = c.recordError(row, err)
+		} else {
+			err = c.makeRowCurrentRecord(row)
+		}
+		if err == nil {
+			if c.recorder != nil {
+				c.recorder.discard(c.reader.InputOffset())
+			}
+			return true
+		}
+		re, ok := err.(*ddataset.RecordError)
+		if !ok || !c.dataset.options.SkipMalformed {
+			c.Close()
+			c.err = err
+			return false
+		}
+		if err := c.skip(re); err != nil {
+			c.Close()
+			c.err = err
+			return false
+		}
 	}
-	return true
+}
+
+// NumSkipped returns the number of rows that have been skipped because
+// they were malformed when Options.SkipMalformed is set
+func (c *DCSVConn) NumSkipped() int64 {
+	return c.numSkipped
 }
 
 // Err returns any errors from the connection
@@ -134,45 +473,235 @@ func (c *DCSVConn) Read() ddataset.Record {
 
 // Close closes the connection
 func (c *DCSVConn) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	// The file is closed before the chunkReader so that it can't be left
+	// waiting for a read to finish
 	err := c.file.Close()
+	if c.chunks != nil {
+		c.chunks.close()
+	}
+	if c.rejects != nil && c.ownsRejects {
+		if rejectsErr := c.rejects.Close(); err == nil {
+			err = rejectsErr
+		}
+	}
+	c.rejects = nil
 	c.file = nil
 	c.reader = nil
+	c.recorder = nil
+	c.chunks = nil
+	return err
+}
+
+// readRow reads the next row from the file
+func (c *DCSVConn) readRow() ([]string, error) {
+	if c.chunks != nil {
+		return c.chunks.read()
+	}
+	return c.reader.Read()
+}
+
+// rowLine returns the line number of the start of the current row
+func (c *DCSVConn) rowLine() int {
+	if c.chunks != nil {
+		return c.chunks.line()
+	}
+	line, _ := c.reader.FieldPos(0)
+	return line + c.lineOffset
+}
+
+// recordError is like DCSV.recordError but allows for the lines before
+// the csv.Reader's input after SeekRecord
+func (c *DCSVConn) recordError(row []string, err error) error {
+	err = c.dataset.recordError(c.recordNum, row, err)
+	if re, ok := err.(*ddataset.RecordError); ok {
+		re.Line += c.lineOffset
+	}
 	return err
 }
 
+// rawRow returns the raw text of the current row
+func (c *DCSVConn) rawRow() []byte {
+	if c.chunks != nil {
+		return c.chunks.raw()
+	}
+	return c.recorder.take(c.reader.InputOffset())
+}
+
+// skip records that the current row has been skipped because of re
+func (c *DCSVConn) skip(re *ddataset.RecordError) error {
+	c.numSkipped++
+	raw := c.rawRow()
+	if c.rejects != nil {
+		if _, err := c.rejects.Write(raw); err != nil {
+			return err
+		}
+		if len(raw) > 0 && raw[len(raw)-1] != '\n' {
+			if _, err := c.rejects.Write([]byte{'\n'}); err != nil {
+				return err
+			}
+		}
+	}
+	if c.onMalformed != nil {
+		c.onMalformed(re, strings.TrimRight(string(raw), "\r\n"))
+	}
+	return nil
+}
+
 func (c *DCSVConn) numFields() int {
 	return c.dataset.numFields
 }
 
 func (c *DCSVConn) makeRowCurrentRecord(row []string) error {
 	fieldNames := c.dataset.Fields()
-	if len(row) != c.dataset.numFields {
-		c.err = ddataset.ErrWrongNumFields
-		c.Close()
-		return c.err
+	if len(row) != c.numFields() {
+		return &ddataset.RecordError{
+			Source:    c.dataset.filename,
+			Record:    c.recordNum,
+			Line:      c.rowLine() + c.dataset.options.SkipLines,
+			NumFields: c.numFields(),
+			NumValues: len(row),
+			Err:       ddataset.ErrWrongNumFields,
+		}
 	}
-	for i, field := range row {
-		c.currentRecord[fieldNames[i]] = dlit.NewString(field)
+	nullToken := c.dataset.options.NullToken
+	for i, fieldName := range fieldNames {
+		field := row[i]
+		if c.fieldColumns != nil {
+			field = row[c.fieldColumns[i]]
+		}
+		if nullToken != "" && field == nullToken {
+			c.currentRecord[fieldName] = ddataset.NewNull()
+		} else {
+			c.currentRecord[fieldName] = dlit.NewString(field)
+		}
 	}
 	return nil
 }
 
-func makeCsvReader(
-	filename string,
-	separator rune,
-	hasHeader bool,
-) (*os.File, *csv.Reader, error) {
-	f, err := os.Open(filename)
+// makeCsvReader opens the file and returns a csv.Reader for it
+// positioned after any skipped lines and header, along with a copy of
+// the header if there is one.  Files compressed with gzip or bzip2 are
+// decompressed as they are read.  If
+// Options.SkipMalformed is set then the number of fields isn't checked
+// by the csv.Reader.  If recordRows is set then a rowRecorder is
+// returned to recover the raw text of rows.
+func (d *DCSV) makeCsvReader(recordRows bool) (
+	io.ReadCloser,
+	*csv.Reader,
+	*rowRecorder,
+	[]string,
+	error,
+) {
+	f, err := d.open()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	src, err := decompress(d.filename, f)
 	if err != nil {
-		return nil, nil, err
+		f.Close()
+		return nil, nil, nil, nil, fmt.Errorf("%s: %w", d.filename, err)
 	}
-	r := csv.NewReader(f)
-	r.Comma = separator
-	if hasHeader {
-		_, err := r.Read()
+	src, err = decode(src, d.options.Encoding)
+	if err != nil {
+		f.Close()
+		return nil, nil, nil, nil, fmt.Errorf("%s: %w", d.filename, err)
+	}
+	if d.options.SkipLines > 0 {
+		br := bufio.NewReader(src)
+		if _, err := skipLines(br, d.options.SkipLines); err != nil {
+			f.Close()
+			return nil, nil, nil, nil, err
+		}
+		src = br
+	}
+	var recorder *rowRecorder
+	if recordRows {
+		recorder = newRowRecorder(src)
+		src = recorder
+	}
+	r := d.newCsvReader(src)
+	if d.options.SkipMalformed {
+		r.FieldsPerRecord = -1
+	}
+	var header []string
+	if d.hasHeader {
+		row, err := r.Read()
 		if err != nil {
-			return nil, nil, err
+			f.Close()
+			return nil, nil, nil, nil, err
 		}
+		header = append([]string{}, row...)
+		if recorder != nil {
+			recorder.discard(r.InputOffset())
+		}
+	}
+	return f, r, recorder, header, nil
+}
+
+// newCsvReader returns a csv.Reader for src configured by the options
+func (d *DCSV) newCsvReader(src io.Reader) *csv.Reader {
+	r := csv.NewReader(src)
+	r.Comma = d.separator
+	r.Comment = d.options.Comment
+	r.LazyQuotes = d.options.LazyQuotes
+	r.TrimLeadingSpace = d.options.TrimLeadingSpace
+	r.ReuseRecord = d.options.ReuseRecord
+	return r
+}
+
+// isParallel returns whether the file is parsed in parallel
+func (d *DCSV) isParallel() bool {
+	return d.options.Workers > 1
+}
+
+// recordsRows returns whether connections need a rowRecorder, either
+// to recover the raw text of malformed rows or to parse in parallel
+func (d *DCSV) recordsRows() bool {
+	return d.options.SkipMalformed || d.isParallel()
+}
+
+// skipLines reads n lines from br and returns the number of bytes read.
+// Reaching the end of the file isn't an error.
+func skipLines(br *bufio.Reader, n int) (int64, error) {
+	numBytes := int64(0)
+	for n > 0 {
+		line, err := br.ReadSlice('\n')
+		numBytes += int64(len(line))
+		switch err {
+		case nil:
+			n--
+		case bufio.ErrBufferFull:
+			// The line is longer than the buffer so keep reading it
+		case io.EOF:
+			return numBytes, nil
+		default:
+			return numBytes, err
+		}
+	}
+	return numBytes, nil
+}
+
+// recordError returns err as a *ddataset.RecordError if it is a
+// *csv.ParseError, otherwise err is returned unchanged
+func (d *DCSV) recordError(recordNum int64, row []string, err error) error {
+	pe, ok := err.(*csv.ParseError)
+	if !ok {
+		return err
+	}
+	re := &ddataset.RecordError{
+		Source: d.filename,
+		Record: recordNum,
+		Line:   pe.StartLine + d.options.SkipLines,
+		Column: pe.Column,
+		Err:    pe.Err,
+	}
+	if pe.Err == csv.ErrFieldCount {
+		re.Column = 0
+		re.NumFields = d.numFields
+		re.NumValues = len(row)
 	}
-	return f, r, err
+	return re
 }
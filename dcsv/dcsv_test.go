@@ -5,9 +5,11 @@ import (
 	"github.com/lawrencewoodman/ddataset"
 	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
 	"github.com/lawrencewoodman/dlit"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"syscall"
 	"testing"
@@ -92,6 +94,28 @@ func TestRelease_error(t *testing.T) {
 	}
 }
 
+func TestSchema(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	schema := ddataset.Schema{
+		{Name: "name", Kind: ddataset.KindString},
+		{Name: "balance", Kind: ddataset.KindInt},
+		{Name: "numCards", Kind: ddataset.KindInt},
+		{Name: "martialStatus", Kind: ddataset.KindString},
+		{Name: "tertiaryEducated", Kind: ddataset.KindBool, Nullable: true},
+		{Name: "success", Kind: ddataset.KindBool},
+	}
+	ds := NewWithSchema(filename, true, ',', schema)
+	if got := ddataset.SchemaOf(ds); !reflect.DeepEqual(got, schema) {
+		t.Errorf("Schema() - got: %v, want: %v", got, schema)
+	}
+	if got := ds.Fields(); !reflect.DeepEqual(got, schema.Names()) {
+		t.Errorf("Fields() - got: %s, want: %s", got, schema.Names())
+	}
+	if got := ds.NumRecords(); got != 10000 {
+		t.Errorf("NumRecords() - got: %d, want: 10000", got)
+	}
+}
+
 func TestFields(t *testing.T) {
 	filename := filepath.Join("fixtures", "bank.csv")
 	fieldNames := []string{
@@ -237,6 +261,95 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestRead_compressed(t *testing.T) {
+	fieldNames := []string{"age", "job", "marital", "education", "default",
+		"balance", "housing", "loan", "contact", "day", "month", "duration",
+		"campaign", "pdays", "previous", "poutcome", "y"}
+	ds := New(filepath.Join("fixtures", "bank.csv"), true, ';', fieldNames)
+	filenames := []string{
+		filepath.Join("fixtures", "bank.csv.gz"),
+		filepath.Join("fixtures", "bank.csv.bz2"),
+		filepath.Join("fixtures", "bank_csv_gz"),
+	}
+	for _, filename := range filenames {
+		cds := New(filename, true, ';', fieldNames)
+		if err := testhelpers.CheckDatasetsEqual(ds, cds); err != nil {
+			t.Errorf("CheckDatasetsEqual - filename: %s, err: %s", filename, err)
+		}
+		if got := cds.NumRecords(); got != 9 {
+			t.Errorf("NumRecords - filename: %s, got: %d, want: 9", filename, got)
+		}
+	}
+}
+
+func TestRead_notCompressed(t *testing.T) {
+	filename := filepath.Join("fixtures", "bzh.csv")
+	wantRecord := ddataset.Record{
+		"a": dlit.NewString("BZh"),
+		"b": dlit.NewString("a"),
+	}
+	ds := New(filename, false, ',', []string{"a", "b"})
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	if !conn.Next() {
+		t.Fatalf("Next - return false early, err: %v", conn.Err())
+	}
+	if record := conn.Read(); !testhelpers.MatchRecords(record, wantRecord) {
+		t.Errorf("Read - got: %s, want: %s", record, wantRecord)
+	}
+}
+
+func TestRead_nullToken(t *testing.T) {
+	filename := filepath.Join("fixtures", "nulls.csv")
+	schema := ddataset.NewSchema([]string{"name", "dept", "age"})
+	wantRecords := []ddataset.Record{
+		ddataset.Record{
+			"name": dlit.MustNew("Fred Wilkins"),
+			"dept": dlit.MustNew("Logistics"),
+			"age":  dlit.MustNew(35),
+		},
+		ddataset.Record{
+			"name": dlit.MustNew("George Eliot"),
+			"dept": ddataset.NewNull(),
+			"age":  dlit.MustNew(""),
+		},
+		ddataset.Record{
+			"name": dlit.MustNew("Mary Terence"),
+			"dept": dlit.MustNew(""),
+			"age":  ddataset.NewNull(),
+		},
+		ddataset.Record{
+			"name": dlit.MustNew("Ned James"),
+			"dept": dlit.MustNew("Shipping"),
+			"age":  dlit.MustNew(41),
+		},
+	}
+	ds := NewWithOptions(filename, true, ',', schema, Options{NullToken: "NA"})
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open() err: %s", err)
+	}
+	defer conn.Close()
+	for _, wantRecord := range wantRecords {
+		if !conn.Next() {
+			t.Fatalf("Next() - return false early")
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, wantRecord) {
+			t.Errorf("Read() got: %s, want: %s", record, wantRecord)
+		}
+	}
+	if conn.Next() {
+		t.Errorf("Next() - return true, despite having finished")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err() err: %s", err)
+	}
+}
+
 func TestErr(t *testing.T) {
 	cases := []struct {
 		filename   string
@@ -246,16 +359,26 @@ func TestErr(t *testing.T) {
 	}{
 		{filepath.Join("fixtures", "invalid_numfields_at_102.csv"), ',',
 			[]string{"band", "score", "team", "points", "rating"},
-			&csv.ParseError{
-				Line:   102,
-				Column: 0,
-				Err:    csv.ErrFieldCount,
+			&ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "invalid_numfields_at_102.csv"),
+				Record:    102,
+				Line:      102,
+				NumFields: 5,
+				NumValues: 4,
+				Err:       csv.ErrFieldCount,
 			}},
 		{filepath.Join("fixtures", "bank.csv"), ';',
 			[]string{"age", "job", "marital", "education", "default", "balance",
 				"housing", "loan", "contact", "day", "month", "duration", "campaign",
 				"pdays", "previous", "poutcome"},
-			ddataset.ErrWrongNumFields},
+			&ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "bank.csv"),
+				Record:    1,
+				Line:      1,
+				NumFields: 16,
+				NumValues: 17,
+				Err:       ddataset.ErrWrongNumFields,
+			}},
 		{filepath.Join("fixtures", "bank.csv"), ';',
 			[]string{"age", "job", "marital", "education", "default", "balance",
 				"housing", "loan", "contact", "day", "month", "duration", "campaign",
@@ -338,13 +461,23 @@ func TestNext_errors(t *testing.T) {
 			[]string{"age", "marital", "education", "default", "balance",
 				"housing", "loan", "contact", "day", "month", "duration", "campaign",
 				"pdays", "previous", "poutcome"}, 0,
-			ddataset.ErrWrongNumFields},
+			&ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "bank.csv"),
+				Record:    1,
+				Line:      1,
+				NumFields: 15,
+				NumValues: 17,
+				Err:       ddataset.ErrWrongNumFields,
+			}},
 		{filepath.Join("fixtures", "invalid_numfields_at_102.csv"), ',',
 			[]string{"band", "score", "team", "points", "rating"}, 101,
-			&csv.ParseError{
-				Line:   102,
-				Column: 0,
-				Err:    csv.ErrFieldCount,
+			&ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "invalid_numfields_at_102.csv"),
+				Record:    102,
+				Line:      102,
+				NumFields: 5,
+				NumValues: 4,
+				Err:       csv.ErrFieldCount,
 			}},
 	}
 	for _, c := range cases {
@@ -375,6 +508,223 @@ func TestNext_errors(t *testing.T) {
 	}
 }
 
+func TestRead_options(t *testing.T) {
+	filename := filepath.Join("fixtures", "options.csv")
+	schema := ddataset.NewSchema([]string{"name", "age", "dept"})
+	options := Options{
+		LazyQuotes:       true,
+		Comment:          '#',
+		TrimLeadingSpace: true,
+		ReuseRecord:      true,
+		SkipLines:        2,
+		NullToken:        "NA",
+	}
+	wantRecords := []ddataset.Record{
+		ddataset.Record{
+			"name": dlit.NewString("Fred"),
+			"age":  dlit.NewString("32"),
+			"dept": dlit.NewString("Logistics"),
+		},
+		ddataset.Record{
+			"name": dlit.NewString("Bob"),
+			"age":  dlit.NewString("41"),
+			"dept": dlit.NewString("Sales \"dept\""),
+		},
+		ddataset.Record{
+			"name": dlit.NewString("George"),
+			"age":  ddataset.NewNull(),
+			"dept": dlit.NewString("Admin"),
+		},
+	}
+	ds := NewWithOptions(filename, true, ',', schema, options)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	gotRecords := []ddataset.Record{}
+	for conn.Next() {
+		gotRecords = append(gotRecords, conn.Read().Clone())
+	}
+	if err := conn.Err(); err != nil {
+		t.Fatalf("Err: %s", err)
+	}
+	if len(gotRecords) != len(wantRecords) {
+		t.Fatalf("Read - got %d records, want: %d",
+			len(gotRecords), len(wantRecords))
+	}
+	for i, wantRecord := range wantRecords {
+		if !testhelpers.MatchRecords(gotRecords[i], wantRecord) {
+			t.Errorf("Read - got: %s, want: %s", gotRecords[i], wantRecord)
+		}
+	}
+}
+
+func TestErr_skipLines(t *testing.T) {
+	filename := filepath.Join("fixtures", "malformed.csv")
+	schema := ddataset.NewSchema([]string{"name", "age", "dept"})
+	wantErr := &ddataset.RecordError{
+		Source:    filename,
+		Record:    2,
+		Line:      3,
+		NumFields: 3,
+		NumValues: 2,
+		Err:       csv.ErrFieldCount,
+	}
+	ds := NewWithOptions(filename, false, ',', schema, Options{SkipLines: 1})
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	for conn.Next() {
+	}
+	if err := conn.Err(); !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("Err - got: %v, want: %s", err, wantErr)
+	}
+}
+
+func TestRead_skipMalformed(t *testing.T) {
+	filename := filepath.Join("fixtures", "malformed.csv")
+	tmpDir, err := ioutil.TempDir("", "dcsv_rejects")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	rejectsFilename := filepath.Join(tmpDir, "rejects.csv")
+	schema := ddataset.NewSchema([]string{"name", "age", "dept"})
+	wantRecords := []ddataset.Record{
+		ddataset.Record{
+			"name": dlit.NewString("Fred"),
+			"age":  dlit.NewString("32"),
+			"dept": dlit.NewString("Logistics"),
+		},
+		ddataset.Record{
+			"name": dlit.NewString("George"),
+			"age":  dlit.NewString("55"),
+			"dept": dlit.NewString("Admin"),
+		},
+	}
+	wantErrs := []*ddataset.RecordError{
+		&ddataset.RecordError{
+			Source:    filename,
+			Record:    2,
+			Line:      3,
+			NumFields: 3,
+			NumValues: 2,
+			Err:       ddataset.ErrWrongNumFields,
+		},
+		&ddataset.RecordError{
+			Source: filename,
+			Record: 3,
+			Line:   4,
+			Column: 7,
+			Err:    csv.ErrQuote,
+		},
+		&ddataset.RecordError{
+			Source:    filename,
+			Record:    4,
+			Line:      5,
+			NumFields: 3,
+			NumValues: 4,
+			Err:       ddataset.ErrWrongNumFields,
+		},
+	}
+	wantRaws := []string{
+		"Bob,41",
+		"Ned,\"2\"7,Shipping",
+		"Mary,29,Shipping,extra",
+	}
+	gotErrs := []*ddataset.RecordError{}
+	gotRaws := []string{}
+	ds := NewWithOptions(filename, true, ',', schema, Options{
+		SkipMalformed: true,
+		OnMalformed: func(err *ddataset.RecordError, raw string) {
+			gotErrs = append(gotErrs, err)
+			gotRaws = append(gotRaws, raw)
+		},
+		RejectsFilename: rejectsFilename,
+	})
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	for _, wantRecord := range wantRecords {
+		if !conn.Next() {
+			t.Fatalf("Next - return false early, err: %v", conn.Err())
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, wantRecord) {
+			t.Errorf("Read - got: %s, want: %s", record, wantRecord)
+		}
+	}
+	if conn.Next() {
+		t.Errorf("Next - return true, despite having finished")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err: %s", err)
+	}
+	if got := conn.(*DCSVConn).NumSkipped(); got != 3 {
+		t.Errorf("NumSkipped - got: %d, want: 3", got)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close: %s", err)
+	}
+
+	if !reflect.DeepEqual(gotErrs, wantErrs) {
+		t.Errorf("OnMalformed - got errs: %v, want: %v", gotErrs, wantErrs)
+	}
+	if !reflect.DeepEqual(gotRaws, wantRaws) {
+		t.Errorf("OnMalformed - got raws: %q, want: %q", gotRaws, wantRaws)
+	}
+	rejects, err := ioutil.ReadFile(rejectsFilename)
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	wantRejects := strings.Join(wantRaws, "\n") + "\n"
+	if string(rejects) != wantRejects {
+		t.Errorf("rejects - got: %q, want: %q", rejects, wantRejects)
+	}
+
+	// NumRecords mustn't write to the rejects file or call OnMalformed
+	if err := os.Remove(rejectsFilename); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if got := ds.NumRecords(); got != 2 {
+		t.Errorf("NumRecords - got: %d, want: 2", got)
+	}
+	if len(gotErrs) != len(wantErrs) {
+		t.Errorf("OnMalformed after NumRecords - got %d calls, want: %d",
+			len(gotErrs), len(wantErrs))
+	}
+	if _, err := os.Stat(rejectsFilename); !os.IsNotExist(err) {
+		t.Errorf("Stat - rejects file created by NumRecords, err: %v", err)
+	}
+
+	// Each connection truncates the rejects file
+	if err := ioutil.WriteFile(rejectsFilename, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	conn, err = ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if !conn.Next() {
+		t.Fatalf("Next - return false early, err: %v", conn.Err())
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close: %s", err)
+	}
+	rejects, err = ioutil.ReadFile(rejectsFilename)
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	wantRejects = ""
+	if string(rejects) != wantRejects {
+		t.Errorf("rejects - got: %q, want: %q", rejects, wantRejects)
+	}
+}
+
 func TestOpenNextRead_goroutines(t *testing.T) {
 	var numGoroutines int
 	filename := filepath.Join("fixtures", "debt.csv")
@@ -420,6 +770,18 @@ func TestOpenNextRead_goroutines(t *testing.T) {
 	}
 }
 
+func TestOpenContext_cancel(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	fieldNames := []string{
+		"name", "balance", "numCards", "martialStatus",
+		"tertiaryEducated", "success",
+	}
+	ds := New(filename, true, ',', fieldNames)
+	if err := testhelpers.CheckOpenContextCancel(ds, 5); err != nil {
+		t.Errorf("CheckOpenContextCancel: %s", err)
+	}
+}
+
 /*************************
  *  Benchmarks
  *************************/
@@ -453,6 +815,41 @@ func BenchmarkOpenNextRead(b *testing.B) {
 	}
 }
 
+func BenchmarkOpenNextRead_parallel(b *testing.B) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	hasHeader := true
+	fieldNames := []string{
+		"name",
+		"balance",
+		"numCards",
+		"martialStatus",
+		"tertiaryEducated",
+		"success",
+	}
+	ds := NewWithOptions(
+		filename,
+		hasHeader,
+		',',
+		ddataset.NewSchema(fieldNames),
+		Options{Workers: 4, ChunkSize: 64 * 1024},
+	)
+	sumBalances := make([]int64, b.N)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sumBalances[i] = testhelpers.SumBalance(ds)
+	}
+	b.StopTimer()
+
+	sumBalance := sumBalances[0]
+	for _, s := range sumBalances {
+		if s != sumBalance {
+			b.Error("sumBalances are not all equal")
+			return
+		}
+	}
+}
+
 func BenchmarkOpenNextRead_goroutines(b *testing.B) {
 	filename := filepath.Join("fixtures", "debt.csv")
 	hasHeader := true
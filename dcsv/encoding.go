@@ -0,0 +1,212 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a CSV file.  Files which aren't
+// UTF-8 are transcoded to UTF-8 as they are read.
+type Encoding int
+
+const (
+	// EncodingUTF8 is UTF-8, which includes ASCII
+	EncodingUTF8 Encoding = iota
+	// EncodingUTF16LE is little-endian UTF-16
+	EncodingUTF16LE
+	// EncodingUTF16BE is big-endian UTF-16
+	EncodingUTF16BE
+	// EncodingWindows1252 is the Windows-1252 code page, a superset of
+	// ISO-8859-1 often used by legacy Windows software
+	EncodingWindows1252
+)
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// String returns the name of the encoding
+func (e Encoding) String() string {
+	switch e {
+	case EncodingUTF8:
+		return "UTF-8"
+	case EncodingUTF16LE:
+		return "UTF-16LE"
+	case EncodingUTF16BE:
+		return "UTF-16BE"
+	case EncodingWindows1252:
+		return "Windows-1252"
+	}
+	return fmt.Sprintf("Encoding(%d)", int(e))
+}
+
+// decode returns a reader which transcodes r from enc to UTF-8.  If r
+// starts with a byte order mark then it is removed and the encoding it
+// indicates is used in place of enc.
+func decode(r io.Reader, enc Encoding) (io.Reader, error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	if bomEnc, bomLen := bomEncoding(br); bomLen > 0 {
+		enc = bomEnc
+		if _, err := br.Discard(bomLen); err != nil {
+			return nil, err
+		}
+	}
+	switch enc {
+	case EncodingUTF8:
+		return br, nil
+	case EncodingUTF16LE:
+		return newUTF16Reader(br, binary.LittleEndian), nil
+	case EncodingUTF16BE:
+		return newUTF16Reader(br, binary.BigEndian), nil
+	case EncodingWindows1252:
+		return newWindows1252Reader(br), nil
+	}
+	return nil, fmt.Errorf("unknown encoding: %s", enc)
+}
+
+// bomEncoding returns the encoding indicated by any byte order mark at
+// the start of br and the length of the byte order mark.  The third byte
+// is only peeked if the first two match the UTF-8 byte order mark, so
+// that a stream with two bytes available isn't waited on.
+func bomEncoding(br *bufio.Reader) (Encoding, int) {
+	start, _ := br.Peek(2)
+	if bytes.Equal(start, utf8BOM[:2]) {
+		start, _ = br.Peek(len(utf8BOM))
+	}
+	switch {
+	case bytes.HasPrefix(start, utf8BOM):
+		return EncodingUTF8, len(utf8BOM)
+	case bytes.HasPrefix(start, utf16LEBOM):
+		return EncodingUTF16LE, len(utf16LEBOM)
+	case bytes.HasPrefix(start, utf16BEBOM):
+		return EncodingUTF16BE, len(utf16BEBOM)
+	}
+	return EncodingUTF8, 0
+}
+
+// transcoder reads from r and uses decode to convert what it reads
+// to UTF-8
+type transcoder struct {
+	r io.Reader
+	// decode appends the UTF-8 encoding of as much of in as it can to
+	// out.  It returns out and the number of bytes of in it used.  If
+	// atEOF is set then all of in must be used.
+	decode func(out []byte, in []byte, atEOF bool) ([]byte, int)
+	in     []byte
+	out    []byte
+	outBuf []byte
+	buf    []byte
+	err    error
+}
+
+func newTranscoder(
+	r io.Reader,
+	decode func(out []byte, in []byte, atEOF bool) ([]byte, int),
+) *transcoder {
+	return &transcoder{
+		r:      r,
+		decode: decode,
+		in:     []byte{},
+		out:    []byte{},
+		outBuf: []byte{},
+		buf:    make([]byte, 4096),
+		err:    nil,
+	}
+}
+
+func (t *transcoder) Read(p []byte) (int, error) {
+	for len(t.out) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+		n, err := t.r.Read(t.buf)
+		t.in = append(t.in, t.buf[:n]...)
+		t.err = err
+		var used int
+		t.outBuf, used = t.decode(t.outBuf[:0], t.in, err != nil)
+		t.out = t.outBuf
+		t.in = append(t.in[:0], t.in[used:]...)
+	}
+	n := copy(p, t.out)
+	t.out = t.out[n:]
+	return n, nil
+}
+
+// newUTF16Reader returns a reader which converts UTF-16 read from r to
+// UTF-8.  Invalid UTF-16 is converted to utf8.RuneError.
+func newUTF16Reader(r io.Reader, order binary.ByteOrder) io.Reader {
+	decode := func(out []byte, in []byte, atEOF bool) ([]byte, int) {
+		i := 0
+		for ; i+1 < len(in); i += 2 {
+			r1 := rune(order.Uint16(in[i:]))
+			if !utf16.IsSurrogate(r1) {
+				out = utf8.AppendRune(out, r1)
+				continue
+			}
+			if i+3 >= len(in) {
+				if !atEOF {
+					// Wait for the rest of the surrogate pair to be read
+					break
+				}
+				out = utf8.AppendRune(out, utf8.RuneError)
+				continue
+			}
+			r2 := rune(order.Uint16(in[i+2:]))
+			if r := utf16.DecodeRune(r1, r2); r != utf8.RuneError {
+				out = utf8.AppendRune(out, r)
+				i += 2
+				continue
+			}
+			out = utf8.AppendRune(out, utf8.RuneError)
+		}
+		if atEOF && i < len(in) {
+			// An odd byte at the end of the file
+			out = utf8.AppendRune(out, utf8.RuneError)
+			i = len(in)
+		}
+		return out, i
+	}
+	return newTranscoder(r, decode)
+}
+
+// windows1252 maps the bytes 0x80 to 0x9f of Windows-1252 to runes.  The
+// bytes which aren't defined are mapped to the C1 control characters
+// with the same value.  The other bytes have the same value as the rune.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// newWindows1252Reader returns a reader which converts Windows-1252 read
+// from r to UTF-8
+func newWindows1252Reader(r io.Reader) io.Reader {
+	decode := func(out []byte, in []byte, atEOF bool) ([]byte, int) {
+		for _, b := range in {
+			switch {
+			case b < utf8.RuneSelf:
+				out = append(out, b)
+			case b < 0xa0:
+				out = utf8.AppendRune(out, windows1252[b-0x80])
+			default:
+				out = utf8.AppendRune(out, rune(b))
+			}
+		}
+		return out, len(in)
+	}
+	return newTranscoder(r, decode)
+}
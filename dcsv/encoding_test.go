@@ -0,0 +1,160 @@
+package dcsv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
+)
+
+func TestRead_encoding(t *testing.T) {
+	cityRecord := func(name, city string) ddataset.Record {
+		return ddataset.Record{
+			"name": dlit.NewString(name),
+			"city": dlit.NewString(city),
+		}
+	}
+	textRecords := []ddataset.Record{
+		cityRecord("Zoë", "Zürich"),
+		cityRecord("O’Brien", "Köln"),
+		cityRecord("José", "São Paulo"),
+	}
+	emojiRecords := []ddataset.Record{
+		cityRecord("Zoë", "Zürich"),
+		cityRecord("Ann 😀", "Bath"),
+	}
+	cases := []struct {
+		filename    string
+		encoding    Encoding
+		wantRecords []ddataset.Record
+	}{
+		{filename: "bom.csv",
+			encoding:    EncodingUTF8,
+			wantRecords: textRecords,
+		},
+		{filename: "windows1252.csv",
+			encoding:    EncodingWindows1252,
+			wantRecords: textRecords,
+		},
+		{filename: "utf16le.csv",
+			encoding:    EncodingUTF8,
+			wantRecords: emojiRecords,
+		},
+		{filename: "utf16le.csv",
+			encoding:    EncodingUTF16BE,
+			wantRecords: emojiRecords,
+		},
+		{filename: "utf16le_nobom.csv",
+			encoding:    EncodingUTF16LE,
+			wantRecords: emojiRecords,
+		},
+		{filename: "utf16be.csv",
+			encoding:    EncodingUTF8,
+			wantRecords: emojiRecords,
+		},
+	}
+	for i, c := range cases {
+		filename := filepath.Join("fixtures", c.filename)
+		options := Options{Encoding: c.encoding, HeaderMatch: HeaderExact}
+		ds, err := NewFromHeader(filename, ',', options)
+		if err != nil {
+			t.Fatalf("(%d) NewFromHeader: %s", i, err)
+		}
+		wantFields := []string{"name", "city"}
+		if got := ds.Fields(); !reflect.DeepEqual(got, wantFields) {
+			t.Errorf("(%d) Fields - got: %q, want: %q", i, got, wantFields)
+		}
+		conn, err := ds.Open()
+		if err != nil {
+			t.Fatalf("(%d) Open: %s", i, err)
+		}
+		gotRecords := []ddataset.Record{}
+		for conn.Next() {
+			gotRecords = append(gotRecords, conn.Read().Clone())
+		}
+		if err := conn.Err(); err != nil {
+			t.Errorf("(%d) Err: %s", i, err)
+		}
+		conn.Close()
+		if len(gotRecords) != len(c.wantRecords) {
+			t.Errorf("(%d) Read - got %d records, want: %d",
+				i, len(gotRecords), len(c.wantRecords))
+			continue
+		}
+		for j, wantRecord := range c.wantRecords {
+			if !testhelpers.MatchRecords(gotRecords[j], wantRecord) {
+				t.Errorf("(%d) Read - got: %s, want: %s",
+					i, gotRecords[j], wantRecord)
+			}
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	utf16le := func(units ...uint16) []byte {
+		b := make([]byte, len(units)*2)
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(b[i*2:], u)
+		}
+		return b
+	}
+	cases := []struct {
+		in       []byte
+		encoding Encoding
+		want     string
+	}{
+		{in: []byte("\xef\xbb\xbfa,b"), encoding: EncodingUTF8, want: "a,b"},
+		{in: []byte("a,b\xef\xbb\xbf"), encoding: EncodingUTF8,
+			want: "a,b\ufeff"},
+		{in: []byte(""), encoding: EncodingUTF8, want: ""},
+		{in: []byte("\xef\xbb"), encoding: EncodingUTF8, want: "\xef\xbb"},
+		{in: []byte("\x80\x81\xa3\xe9\xff"), encoding: EncodingWindows1252,
+			want: "€\u0081£éÿ"},
+		{in: utf16le(0xfeff, 'a', 0xd83d, 0xde00, 'b'),
+			encoding: EncodingUTF8,
+			want:     "a😀b",
+		},
+		// An unpaired high surrogate
+		{in: utf16le('a', 0xd83d, 'b'), encoding: EncodingUTF16LE,
+			want: "a�b"},
+		// An unpaired low surrogate
+		{in: utf16le('a', 0xde00, 'b'), encoding: EncodingUTF16LE,
+			want: "a�b"},
+		// A high surrogate at the end
+		{in: utf16le('a', 0xd83d), encoding: EncodingUTF16LE,
+			want: "a�"},
+		// An odd byte at the end
+		{in: append(utf16le('a'), 'b'), encoding: EncodingUTF16LE,
+			want: "a�"},
+		{in: []byte{0, 'a', 0xd8, 0x3d, 0xde, 0x00}, encoding: EncodingUTF16BE,
+			want: "a😀"},
+	}
+	for i, c := range cases {
+		r, err := decode(iotest.OneByteReader(bytes.NewReader(c.in)), c.encoding)
+		if err != nil {
+			t.Fatalf("(%d) decode: %s", i, err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("(%d) ReadAll: %s", i, err)
+		}
+		if string(got) != c.want {
+			t.Errorf("(%d) decode - got: %q, want: %q", i, got, c.want)
+		}
+	}
+}
+
+func TestDecode_errors(t *testing.T) {
+	_, err := decode(bytes.NewReader([]byte("a,b")), Encoding(99))
+	wantErr := "unknown encoding: Encoding(99)"
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("decode - got: %v, want: %s", err, wantErr)
+	}
+}
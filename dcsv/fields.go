@@ -0,0 +1,65 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"fmt"
+
+	"github.com/lawrencewoodman/ddataset"
+)
+
+// projectSchema returns the part of schema for the fields named in
+// fields, in that order, along with the index in schema of each field.
+// If fields is empty then schema is returned with nil indexes.  If a
+// field isn't in schema or is named twice then an error is returned along
+// with a Schema created from fields, so that the Dataset still reports
+// the fields asked for.
+func projectSchema(
+	schema ddataset.Schema,
+	fields []string,
+) (ddataset.Schema, []int, error) {
+	if len(fields) == 0 {
+		return schema, nil, nil
+	}
+	fieldIndex := make(map[string]int, len(schema))
+	for i, f := range schema {
+		fieldIndex[f.Name] = i
+	}
+	projected := make(ddataset.Schema, len(fields))
+	indexes := make([]int, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for i, name := range fields {
+		j, ok := fieldIndex[name]
+		if !ok {
+			return ddataset.NewSchema(fields), nil,
+				fmt.Errorf("fields has unknown field: %s", name)
+		}
+		if seen[name] {
+			return ddataset.NewSchema(fields), nil,
+				fmt.Errorf("fields has duplicate field: %s", name)
+		}
+		seen[name] = true
+		projected[i] = schema[j]
+		indexes[i] = j
+	}
+	return projected, indexes, nil
+}
+
+// projectColumns returns the column each field returned by Fields is read
+// from given the column each field of the file is read from, as returned
+// by matchHeader.  It returns nil if the fields are read in order.
+func (d *DCSV) projectColumns(fieldColumns []int) []int {
+	if d.fieldIndexes == nil {
+		return fieldColumns
+	}
+	columns := make([]int, len(d.fieldIndexes))
+	for i, j := range d.fieldIndexes {
+		if fieldColumns != nil {
+			columns[i] = fieldColumns[j]
+		} else {
+			columns[i] = j
+		}
+	}
+	return columns
+}
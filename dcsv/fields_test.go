@@ -0,0 +1,331 @@
+package dcsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+)
+
+func TestRead_fields(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	fieldNames := []string{
+		"name",
+		"balance",
+		"numCards",
+		"martialStatus",
+		"tertiaryEducated",
+		"success",
+	}
+	cases := []struct {
+		fieldNames []string
+		options    Options
+	}{
+		{fieldNames: fieldNames,
+			options: Options{Fields: []string{"balance"}},
+		},
+		{fieldNames: fieldNames,
+			options: Options{Fields: []string{"success", "name", "numCards"}},
+		},
+		{fieldNames: fieldNames,
+			options: Options{
+				Fields:  []string{"success", "balance"},
+				Workers: 3,
+			},
+		},
+		{fieldNames: []string{
+			"success",
+			"tertiary_educated",
+			"marital_status",
+			"num_cards",
+			"balance",
+			"name",
+		},
+			options: Options{
+				Fields:      []string{"name", "num_cards"},
+				HeaderMatch: HeaderByName,
+			},
+		},
+	}
+	for i, c := range cases {
+		schema := ddataset.NewSchema(c.fieldNames)
+		for j, f := range schema {
+			if f.Name == "balance" {
+				schema[j].Kind = ddataset.KindInt
+			}
+		}
+		options := c.options
+		options.Fields = nil
+		allDS := NewWithOptions(filename, true, ',', schema, options)
+		ds := NewWithOptions(filename, true, ',', schema, c.options)
+		if got := ds.Fields(); !reflect.DeepEqual(got, c.options.Fields) {
+			t.Errorf("(%d) Fields - got: %q, want: %q", i, got, c.options.Fields)
+		}
+		gotSchema := ddataset.SchemaOf(ds)
+		if got := gotSchema.Names(); !reflect.DeepEqual(got, c.options.Fields) {
+			t.Errorf("(%d) Schema - got: %q, want: %q", i, got, c.options.Fields)
+		}
+		for _, f := range gotSchema {
+			if f.Name == "balance" && f.Kind != ddataset.KindInt {
+				t.Errorf("(%d) Schema - balance kind got: %s, want: %s",
+					i, f.Kind, ddataset.KindInt)
+			}
+		}
+		if err := checkProjection(ds, allDS, c.options.Fields); err != nil {
+			t.Errorf("(%d) checkProjection: %s", i, err)
+		}
+	}
+}
+
+func TestNewFromHeader_fields(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	wantFields := []string{"marital_status", "name"}
+	ds, err := NewFromHeader(filename, ',', Options{Fields: wantFields})
+	if err != nil {
+		t.Fatalf("NewFromHeader: %s", err)
+	}
+	if got := ds.Fields(); !reflect.DeepEqual(got, wantFields) {
+		t.Errorf("Fields - got: %q, want: %q", got, wantFields)
+	}
+	allDS, err := NewFromHeader(filename, ',', Options{})
+	if err != nil {
+		t.Fatalf("NewFromHeader: %s", err)
+	}
+	if err := checkProjection(ds, allDS, wantFields); err != nil {
+		t.Errorf("checkProjection: %s", err)
+	}
+}
+
+func TestNewMulti_fields(t *testing.T) {
+	filenames := []string{
+		filepath.Join("fixtures", "sales", "sales-2026-01.csv"),
+		filepath.Join("fixtures", "sales", "sales-2026-02.csv"),
+	}
+	wantFields := []string{"amount", "region"}
+	ds := NewMulti(filenames, true, ',', salesSchema, Options{Fields: wantFields})
+	if got := ds.Fields(); !reflect.DeepEqual(got, wantFields) {
+		t.Errorf("Fields - got: %q, want: %q", got, wantFields)
+	}
+	if got := ddataset.SchemaOf(ds).Names(); !reflect.DeepEqual(got, wantFields) {
+		t.Errorf("Schema - got: %q, want: %q", got, wantFields)
+	}
+	allDS := NewMulti(filenames, true, ',', salesSchema, Options{})
+	if err := checkProjection(ds, allDS, wantFields); err != nil {
+		t.Errorf("checkProjection: %s", err)
+	}
+}
+
+func TestRead_fields_errors(t *testing.T) {
+	filename := filepath.Join("fixtures", "malformed.csv")
+	schema := ddataset.NewSchema([]string{"name", "age", "dept"})
+	cases := []struct {
+		fields  []string
+		wantErr error
+	}{
+		{fields: []string{"name", "salary"},
+			wantErr: errors.New("fields has unknown field: salary"),
+		},
+		{fields: []string{"age", "name", "age"},
+			wantErr: errors.New("fields has duplicate field: age"),
+		},
+	}
+	for i, c := range cases {
+		ds := NewWithOptions(filename, true, ',', schema, Options{Fields: c.fields})
+		if got := ds.Fields(); !reflect.DeepEqual(got, c.fields) {
+			t.Errorf("(%d) Fields - got: %q, want: %q", i, got, c.fields)
+		}
+		_, err := ds.Open()
+		if !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) Open - got: %v, want: %s", i, err, c.wantErr)
+		}
+		_, err = NewFromHeader(filename, ',', Options{Fields: c.fields})
+		if !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) NewFromHeader - got: %v, want: %s", i, err, c.wantErr)
+		}
+	}
+
+	// Rows are still checked against every field
+	ds := NewWithOptions(
+		filename,
+		true,
+		',',
+		schema,
+		Options{Fields: []string{"name"}},
+	)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	for conn.Next() {
+	}
+	wantErr := &ddataset.RecordError{
+		Source:    filename,
+		Record:    2,
+		Line:      3,
+		NumFields: 3,
+		NumValues: 2,
+		Err:       csv.ErrFieldCount,
+	}
+	if err := conn.Err(); !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("Err - got: %v, want: %v", err, wantErr)
+	}
+}
+
+func TestNewMulti_fields_errors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dcsv_fields")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	rejectsFilename := filepath.Join(tmpDir, "rejects.csv")
+	filenames := []string{
+		filepath.Join("fixtures", "sales", "sales-2026-01.csv"),
+		filepath.Join("fixtures", "sales", "sales-2026-02.csv"),
+	}
+	cases := []struct {
+		filenames []string
+		fields    []string
+		wantErr   error
+	}{
+		{filenames: filenames,
+			fields:  []string{"amount", "profit"},
+			wantErr: errors.New("fields has unknown field: profit"),
+		},
+		{filenames: []string{},
+			fields:  []string{"region", "region"},
+			wantErr: errors.New("fields has duplicate field: region"),
+		},
+	}
+	for i, c := range cases {
+		options := Options{
+			Fields:          c.fields,
+			SkipMalformed:   true,
+			RejectsFilename: rejectsFilename,
+		}
+		ds := NewMulti(c.filenames, true, ',', salesSchema, options)
+		if got := ds.Fields(); !reflect.DeepEqual(got, c.fields) {
+			t.Errorf("(%d) Fields - got: %q, want: %q", i, got, c.fields)
+		}
+		_, err := ds.Open()
+		if !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) Open - got: %v, want: %s", i, err, c.wantErr)
+		}
+		if got := ds.NumRecords(); got != -1 {
+			t.Errorf("(%d) NumRecords - got: %d, want: -1", i, got)
+		}
+		if _, err := os.Stat(rejectsFilename); !os.IsNotExist(err) {
+			t.Errorf("(%d) Stat - rejects file created, err: %v", i, err)
+		}
+	}
+}
+
+func BenchmarkOpenNextRead_fields(b *testing.B) {
+	tmpDir, err := ioutil.TempDir("", "dcsv_fields")
+	if err != nil {
+		b.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	filename := filepath.Join(tmpDir, "wide.csv")
+	fieldNames, err := writeWideCsv(filename, 200, 1000)
+	if err != nil {
+		b.Fatalf("writeWideCsv: %s", err)
+	}
+	schema := ddataset.NewSchema(fieldNames)
+	cases := []struct {
+		name   string
+		fields []string
+	}{
+		{name: "all", fields: nil},
+		{name: "five", fields: fieldNames[10:15]},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			options := Options{Fields: c.fields, ReuseRecord: true}
+			ds := NewWithOptions(filename, true, ',', schema, options)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				conn, err := ds.Open()
+				if err != nil {
+					b.Fatalf("Open: %s", err)
+				}
+				for conn.Next() {
+				}
+				if err := conn.Err(); err != nil {
+					b.Fatalf("Err: %s", err)
+				}
+				conn.Close()
+			}
+		})
+	}
+}
+
+// checkProjection checks that ds returns the same records as allDS but
+// with only the fields named in fields
+func checkProjection(ds, allDS ddataset.Dataset, fields []string) error {
+	conn, err := ds.Open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	allConn, err := allDS.Open()
+	if err != nil {
+		return err
+	}
+	defer allConn.Close()
+	numRecords := 0
+	for allConn.Next() {
+		if !conn.Next() {
+			return fmt.Errorf("Next - return false early, err: %v", conn.Err())
+		}
+		all := allConn.Read()
+		want := make(ddataset.Record, len(fields))
+		for _, field := range fields {
+			want[field] = all[field]
+		}
+		if got := conn.Read(); !testhelpers.MatchRecords(got, want) {
+			return fmt.Errorf("Read - got: %s, want: %s", got, want)
+		}
+		numRecords++
+	}
+	if err := allConn.Err(); err != nil {
+		return err
+	}
+	if conn.Next() {
+		return errors.New("Next - got: true, want: false")
+	}
+	if numRecords == 0 {
+		return errors.New("no records read")
+	}
+	return conn.Err()
+}
+
+// writeWideCsv writes a CSV file with a header to filename with
+// numFields fields and numRecords records and returns the field names
+func writeWideCsv(filename string, numFields, numRecords int) ([]string, error) {
+	fieldNames := make([]string, numFields)
+	for i := range fieldNames {
+		fieldNames[i] = fmt.Sprintf("field%d", i)
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.Join(fieldNames, ","))
+	sb.WriteByte('\n')
+	row := make([]string, numFields)
+	for i := 0; i < numRecords; i++ {
+		for j := range row {
+			row[j] = fmt.Sprintf("%d", i*numFields+j)
+		}
+		sb.WriteString(strings.Join(row, ","))
+		sb.WriteByte('\n')
+	}
+	return fieldNames, ioutil.WriteFile(filename, []byte(sb.String()), 0644)
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HeaderMatch determines how the header of a CSV file is checked against
+// the field names of a DCSV Dataset when a connection is opened
+type HeaderMatch int
+
+const (
+	// HeaderIgnore doesn't check the header
+	HeaderIgnore HeaderMatch = iota
+	// HeaderExact requires the header to be the same as the field names
+	HeaderExact
+	// HeaderCaseInsensitive requires the header to be the same as the
+	// field names ignoring case
+	HeaderCaseInsensitive
+	// HeaderByName requires the header to contain each of the field names
+	// once in any order.  The fields are read from the columns with the
+	// matching names.
+	HeaderByName
+)
+
+// matchHeader checks header against the field names of the Dataset
+// using Options.HeaderMatch.  It returns the index of the column that
+// each field is read from, or nil if they are read in order.
+func (d *DCSV) matchHeader(header []string) ([]int, error) {
+	match := d.options.HeaderMatch
+	if match == HeaderIgnore {
+		return nil, nil
+	}
+	if !d.hasHeader {
+		return nil, fmt.Errorf("can't match header as file has no header: %s",
+			d.filename)
+	}
+	if len(header) != d.numFields {
+		return nil, fmt.Errorf(
+			"header has wrong number of fields: %s, got: %d, want: %d",
+			d.filename, len(header), d.numFields,
+		)
+	}
+	switch match {
+	case HeaderExact, HeaderCaseInsensitive:
+		for i, name := range header {
+			want := d.fieldNames[i]
+			if name == want ||
+				(match == HeaderCaseInsensitive && strings.EqualFold(name, want)) {
+				continue
+			}
+			return nil, fmt.Errorf(
+				"header field doesn't match: %s, field: %d, got: %s, want: %s",
+				d.filename, i+1, name, want,
+			)
+		}
+		return nil, nil
+	case HeaderByName:
+		return d.mapHeaderByName(header)
+	}
+	return nil, fmt.Errorf("unknown header match: %d", match)
+}
+
+// mapHeaderByName returns the index of the column in header that each
+// field is read from
+func (d *DCSV) mapHeaderByName(header []string) ([]int, error) {
+	if err := checkHeaderUnique(d.filename, header); err != nil {
+		return nil, err
+	}
+	columnIndex := make(map[string]int, len(header))
+	for i, name := range header {
+		columnIndex[name] = i
+	}
+	fieldColumns := make([]int, len(d.fieldNames))
+	for i, fieldName := range d.fieldNames {
+		j, ok := columnIndex[fieldName]
+		if !ok {
+			return nil, fmt.Errorf("header is missing field: %s, field: %s",
+				d.filename, fieldName)
+		}
+		fieldColumns[i] = j
+	}
+	return fieldColumns, nil
+}
+
+// checkHeaderUnique returns an error if a field name appears more
+// than once in header
+func checkHeaderUnique(filename string, header []string) error {
+	seen := make(map[string]bool, len(header))
+	for _, name := range header {
+		if seen[name] {
+			return fmt.Errorf("header has duplicate field: %s, field: %s",
+				filename, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
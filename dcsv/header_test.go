@@ -0,0 +1,201 @@
+package dcsv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
+)
+
+func TestNewFromHeader(t *testing.T) {
+	filename := filepath.Join("fixtures", "nulls.csv")
+	wantFields := []string{"name", "dept", "age"}
+	wantRecord := ddataset.Record{
+		"name": dlit.NewString("Fred Wilkins"),
+		"dept": dlit.NewString("Logistics"),
+		"age":  dlit.NewString("35"),
+	}
+	ds, err := NewFromHeader(filename, ',', Options{HeaderMatch: HeaderExact})
+	if err != nil {
+		t.Fatalf("NewFromHeader: %s", err)
+	}
+	if got := ds.Fields(); !reflect.DeepEqual(got, wantFields) {
+		t.Errorf("Fields - got: %v, want: %v", got, wantFields)
+	}
+	if got := ds.NumRecords(); got != 4 {
+		t.Errorf("NumRecords - got: %d, want: 4", got)
+	}
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	if !conn.Next() {
+		t.Fatalf("Next - return false early, err: %v", conn.Err())
+	}
+	if record := conn.Read(); !testhelpers.MatchRecords(record, wantRecord) {
+		t.Errorf("Read - got: %s, want: %s", record, wantRecord)
+	}
+}
+
+func TestNewFromHeader_errors(t *testing.T) {
+	_, err := NewFromHeader("missing.csv", ',', Options{})
+	wantPathErr := &os.PathError{
+		Op:   "open",
+		Path: "missing.csv",
+		Err:  syscall.ENOENT,
+	}
+	if err := testhelpers.CheckPathErrorMatch(err, wantPathErr); err != nil {
+		t.Errorf("NewFromHeader: %s", err)
+	}
+
+	filename := filepath.Join("fixtures", "dupheader.csv")
+	_, err = NewFromHeader(filename, ',', Options{})
+	wantErr := errors.New(
+		"header has duplicate field: " + filename + ", field: name",
+	)
+	if !testhelpers.ErrorMatch(err, wantErr) {
+		t.Errorf("NewFromHeader - got err: %v, want: %s", err, wantErr)
+	}
+}
+
+func TestOpen_headerMatch(t *testing.T) {
+	filename := filepath.Join("fixtures", "nulls.csv")
+	cases := []struct {
+		fieldNames  []string
+		hasHeader   bool
+		headerMatch HeaderMatch
+		wantRecord  ddataset.Record
+	}{
+		{fieldNames: []string{"name", "dept", "age"},
+			hasHeader:   true,
+			headerMatch: HeaderExact,
+			wantRecord: ddataset.Record{
+				"name": dlit.NewString("Fred Wilkins"),
+				"dept": dlit.NewString("Logistics"),
+				"age":  dlit.NewString("35"),
+			},
+		},
+		{fieldNames: []string{"Name", "DEPT", "age"},
+			hasHeader:   true,
+			headerMatch: HeaderCaseInsensitive,
+			wantRecord: ddataset.Record{
+				"Name": dlit.NewString("Fred Wilkins"),
+				"DEPT": dlit.NewString("Logistics"),
+				"age":  dlit.NewString("35"),
+			},
+		},
+		{fieldNames: []string{"age", "name", "dept"},
+			hasHeader:   true,
+			headerMatch: HeaderByName,
+			wantRecord: ddataset.Record{
+				"name": dlit.NewString("Fred Wilkins"),
+				"dept": dlit.NewString("Logistics"),
+				"age":  dlit.NewString("35"),
+			},
+		},
+		{fieldNames: []string{"a", "b", "c"},
+			hasHeader:   true,
+			headerMatch: HeaderIgnore,
+			wantRecord: ddataset.Record{
+				"a": dlit.NewString("Fred Wilkins"),
+				"b": dlit.NewString("Logistics"),
+				"c": dlit.NewString("35"),
+			},
+		},
+	}
+	for i, c := range cases {
+		ds := NewWithOptions(
+			filename,
+			c.hasHeader,
+			',',
+			ddataset.NewSchema(c.fieldNames),
+			Options{HeaderMatch: c.headerMatch},
+		)
+		conn, err := ds.Open()
+		if err != nil {
+			t.Fatalf("(%d) Open: %s", i, err)
+		}
+		if !conn.Next() {
+			t.Fatalf("(%d) Next - return false early, err: %v", i, conn.Err())
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, c.wantRecord) {
+			t.Errorf("(%d) Read - got: %s, want: %s", i, record, c.wantRecord)
+		}
+		conn.Close()
+	}
+}
+
+func TestOpen_headerMatch_errors(t *testing.T) {
+	filename := filepath.Join("fixtures", "nulls.csv")
+	cases := []struct {
+		filename    string
+		fieldNames  []string
+		hasHeader   bool
+		headerMatch HeaderMatch
+		wantErr     error
+	}{
+		{filename: filename,
+			fieldNames:  []string{"Name", "dept", "age"},
+			hasHeader:   true,
+			headerMatch: HeaderExact,
+			wantErr: errors.New("header field doesn't match: " + filename +
+				", field: 1, got: name, want: Name"),
+		},
+		{filename: filename,
+			fieldNames:  []string{"name", "dpt", "age"},
+			hasHeader:   true,
+			headerMatch: HeaderCaseInsensitive,
+			wantErr: errors.New("header field doesn't match: " + filename +
+				", field: 2, got: dept, want: dpt"),
+		},
+		{filename: filename,
+			fieldNames:  []string{"name", "dept"},
+			hasHeader:   true,
+			headerMatch: HeaderExact,
+			wantErr: errors.New("header has wrong number of fields: " +
+				filename + ", got: 3, want: 2"),
+		},
+		{filename: filename,
+			fieldNames:  []string{"age", "name", "salary"},
+			hasHeader:   true,
+			headerMatch: HeaderByName,
+			wantErr: errors.New("header is missing field: " + filename +
+				", field: salary"),
+		},
+		{filename: filepath.Join("fixtures", "dupheader.csv"),
+			fieldNames:  []string{"name", "age", "surname"},
+			hasHeader:   true,
+			headerMatch: HeaderByName,
+			wantErr: errors.New("header has duplicate field: " +
+				filepath.Join("fixtures", "dupheader.csv") + ", field: name"),
+		},
+		{filename: filename,
+			fieldNames:  []string{"name", "dept", "age"},
+			hasHeader:   false,
+			headerMatch: HeaderExact,
+			wantErr: errors.New("can't match header as file has no header: " +
+				filename),
+		},
+	}
+	for i, c := range cases {
+		ds := NewWithOptions(
+			c.filename,
+			c.hasHeader,
+			',',
+			ddataset.NewSchema(c.fieldNames),
+			Options{HeaderMatch: c.headerMatch},
+		)
+		_, err := ds.Open()
+		if !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) Open - got err: %v, want: %s", i, err, c.wantErr)
+		}
+	}
+}
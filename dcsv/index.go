@@ -0,0 +1,344 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/lawrencewoodman/ddataset"
+)
+
+// defaultIndexInterval is the number of records between each offset
+// stored in an index if Options.IndexInterval isn't set
+const defaultIndexInterval = 1000
+
+// indexVersion is the version of the index file format
+const indexVersion = 1
+
+// index records the position of every Interval'th record of a CSV file
+// so that the records can be counted and seeked to without reading the
+// whole file
+type index struct {
+	Version int `json:"version"`
+	// Size and ModTime are those of the CSV file when the index was built
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"modTime"`
+	// Key describes the options the file was read with
+	Key        string       `json:"key"`
+	Interval   int          `json:"interval"`
+	NumRecords int64        `json:"numRecords"`
+	Entries    []indexEntry `json:"entries"`
+}
+
+// indexEntry is the position of the start of a record
+type indexEntry struct {
+	// Offset is the byte offset in the file
+	Offset int64 `json:"offset"`
+	// Row is the number of rows before the record including any skipped
+	Row int64 `json:"row"`
+	// Line is the number of lines before the record not counting
+	// Options.SkipLines
+	Line int `json:"line"`
+}
+
+// SeekRecord positions the connection so that the next call to Next
+// moves to record n, where the first record is 0.  It requires
+// Options.IndexFilename to be set and builds the index if needed.
+func (c *DCSVConn) SeekRecord(n int64) error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.reader == nil {
+		return ddataset.ErrConnClosed
+	}
+	d := c.dataset
+	if d.options.IndexFilename == "" {
+		return fmt.Errorf("can't seek without an index: %s", d.filename)
+	}
+	if _, ok := c.file.(io.Seeker); !ok {
+		return fmt.Errorf("can't seek as file isn't seekable: %s", d.filename)
+	}
+	idx, err := d.loadIndex()
+	if err != nil {
+		return err
+	}
+	if n < 0 || n >= idx.NumRecords {
+		return fmt.Errorf("record doesn't exist: %s, record: %d", d.filename, n)
+	}
+	interval := int64(idx.Interval)
+	if err := c.seek(idx.Entries[n/interval]); err != nil {
+		c.Close()
+		c.err = err
+		return err
+	}
+	for i := n % interval; i > 0; i-- {
+		if !c.Next() {
+			if c.err != nil {
+				return c.err
+			}
+			return fmt.Errorf("record doesn't exist: %s, record: %d",
+				d.filename, n)
+		}
+	}
+	return nil
+}
+
+// seek positions the connection at entry
+func (c *DCSVConn) seek(entry indexEntry) error {
+	d := c.dataset
+	if c.chunks != nil {
+		// The file is closed before the chunkReader so that it can't be
+		// left waiting for a read to finish, and is then opened again
+		err := c.file.Close()
+		c.chunks.close()
+		c.chunks = nil
+		if err != nil {
+			return err
+		}
+		f, err := d.open()
+		if err != nil {
+			return err
+		}
+		c.file = f
+	}
+	file, ok := c.file.(io.Seeker)
+	if !ok {
+		return fmt.Errorf("can't seek as file isn't seekable: %s", d.filename)
+	}
+	if _, err := file.Seek(entry.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	fieldsPerRecord := d.numFields
+	if d.options.SkipMalformed {
+		fieldsPerRecord = -1
+	}
+	c.recorder = nil
+	if d.isParallel() {
+		c.chunks = newChunkReader(
+			d,
+			c.file,
+			entry.Line+1,
+			fieldsPerRecord,
+			fieldsPerRecord,
+		)
+		c.lineOffset = 0
+	} else {
+		var src io.Reader = c.file
+		if d.recordsRows() {
+			c.recorder = newRowRecorder(src)
+			src = c.recorder
+		}
+		c.reader = d.newCsvReader(src)
+		c.reader.FieldsPerRecord = fieldsPerRecord
+		c.lineOffset = entry.Line
+	}
+	c.recordNum = entry.Row
+	return nil
+}
+
+// loadIndex returns the index for the CSV file.  If the index held by d
+// or stored in Options.IndexFilename doesn't match the file then a new
+// index is built and stored.
+func (d *DCSV) loadIndex() (*index, error) {
+	if d.stat == nil {
+		return nil, fmt.Errorf(
+			"can't index file without a size and modification time: %s",
+			d.filename,
+		)
+	}
+	fi, err := d.stat()
+	if err != nil {
+		return nil, err
+	}
+	d.indexMu.Lock()
+	defer d.indexMu.Unlock()
+	if d.index != nil && d.index.matches(d, fi) {
+		return d.index, nil
+	}
+	idx, err := readIndex(d.options.IndexFilename)
+	if err == nil && idx.matches(d, fi) {
+		d.index = idx
+		return idx, nil
+	}
+	idx, err = d.buildIndex(fi)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeIndex(d.options.IndexFilename, idx); err != nil {
+		return nil, err
+	}
+	d.index = idx
+	return idx, nil
+}
+
+// buildIndex builds an index for the CSV file by reading it
+func (d *DCSV) buildIndex(fi fs.FileInfo) (*index, error) {
+	f, err := d.open()
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	if isCompressed(d.filename, br) {
+		f.Close()
+		return nil, fmt.Errorf("can't index compressed file: %s", d.filename)
+	}
+	enc, bomLen := bomEncoding(br)
+	if bomLen == 0 {
+		enc = d.options.Encoding
+	}
+	if enc != EncodingUTF8 {
+		f.Close()
+		return nil, fmt.Errorf(
+			"can't index file which isn't UTF-8: %s, encoding: %s",
+			d.filename, enc,
+		)
+	}
+	if _, err := br.Discard(bomLen); err != nil {
+		f.Close()
+		return nil, err
+	}
+	skipped, err := skipLines(br, d.options.SkipLines)
+	skipped += int64(bomLen)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	options := d.options
+	options.Workers = 0
+	options.OnMalformed = nil
+	sd := newDCSV(
+		d.filename,
+		d.hasHeader,
+		d.separator,
+		d.fieldNames,
+		d.schema,
+		options,
+	)
+	sd.open = d.open
+	c, err := sd.openConn(context.Background(), true)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	idx := &index{
+		Version:    indexVersion,
+		Size:       fi.Size(),
+		ModTime:    fi.ModTime().UnixNano(),
+		Key:        d.indexKey(),
+		Interval:   d.indexInterval(),
+		NumRecords: 0,
+		Entries:    []indexEntry{},
+	}
+	for {
+		entry := indexEntry{
+			Offset: skipped + c.reader.InputOffset(),
+			Row:    c.recordNum,
+			Line:   c.recorder.numLines,
+		}
+		if !c.Next() {
+			break
+		}
+		if idx.NumRecords%int64(idx.Interval) == 0 {
+			idx.Entries = append(idx.Entries, entry)
+		}
+		idx.NumRecords++
+	}
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	endFi, err := d.stat()
+	if err != nil {
+		return nil, err
+	}
+	if endFi.Size() != fi.Size() || !endFi.ModTime().Equal(fi.ModTime()) {
+		return nil, fmt.Errorf("file changed while building index: %s",
+			d.filename)
+	}
+	return idx, nil
+}
+
+// matches returns whether the index is for the CSV file described by fi
+// read with the options of d
+func (idx *index) matches(d *DCSV, fi fs.FileInfo) bool {
+	return idx.Version == indexVersion &&
+		idx.Size == fi.Size() &&
+		idx.ModTime == fi.ModTime().UnixNano() &&
+		idx.Key == d.indexKey() &&
+		idx.Interval == d.indexInterval() &&
+		int64(len(idx.Entries)) ==
+			(idx.NumRecords+int64(idx.Interval)-1)/int64(idx.Interval)
+}
+
+// indexKey returns a description of the options which affect where the
+// records of the file are
+func (d *DCSV) indexKey() string {
+	return fmt.Sprintf(
+		"hasHeader: %t, separator: %q, numFields: %d, comment: %q, "+
+			"lazyQuotes: %t, trimLeadingSpace: %t, skipLines: %d, "+
+			"skipMalformed: %t",
+		d.hasHeader,
+		d.separator,
+		d.numFields,
+		d.options.Comment,
+		d.options.LazyQuotes,
+		d.options.TrimLeadingSpace,
+		d.options.SkipLines,
+		d.options.SkipMalformed,
+	)
+}
+
+func (d *DCSV) indexInterval() int {
+	if d.options.IndexInterval < 1 {
+		return defaultIndexInterval
+	}
+	return d.options.IndexInterval
+}
+
+func readIndex(filename string) (*index, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx := &index{}
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// writeIndex writes idx to a temporary file which is then renamed to
+// filename, so that a partly written index is never read
+func writeIndex(filename string, idx *index) error {
+	f, err := os.CreateTemp(
+		filepath.Dir(filename),
+		filepath.Base(filename)+".tmp*",
+	)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = json.NewEncoder(w).Encode(idx)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
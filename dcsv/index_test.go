@@ -0,0 +1,334 @@
+package dcsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+)
+
+var indexCases = []struct {
+	filename   string
+	hasHeader  bool
+	separator  rune
+	fieldNames []string
+	options    Options
+}{
+	{filename: filepath.Join("fixtures", "debt.csv"),
+		hasHeader: true,
+		separator: ',',
+		fieldNames: []string{
+			"name",
+			"balance",
+			"numCards",
+			"martialStatus",
+			"tertiaryEducated",
+			"success",
+		},
+	},
+	{filename: filepath.Join("fixtures", "malformed.csv"),
+		hasHeader:  true,
+		separator:  ',',
+		fieldNames: []string{"name", "age", "dept"},
+		options:    Options{SkipMalformed: true},
+	},
+	{filename: filepath.Join("fixtures", "options.csv"),
+		hasHeader:  true,
+		separator:  ',',
+		fieldNames: []string{"name", "age", "dept"},
+		options: Options{
+			LazyQuotes:       true,
+			Comment:          '#',
+			TrimLeadingSpace: true,
+			SkipLines:        2,
+			NullToken:        "NA",
+		},
+	},
+	{filename: filepath.Join("fixtures", "quoted.csv"),
+		hasHeader:  true,
+		separator:  ',',
+		fieldNames: []string{"id", "name", "notes"},
+		options:    Options{Comment: '#', SkipMalformed: true},
+	},
+	{filename: filepath.Join("fixtures", "quoted.csv"),
+		hasHeader:  true,
+		separator:  ',',
+		fieldNames: []string{"id", "name", "notes"},
+		options:    Options{SkipMalformed: true},
+	},
+	{filename: filepath.Join("fixtures", "bom.csv"),
+		hasHeader:  true,
+		separator:  ',',
+		fieldNames: []string{"name", "city"},
+	},
+}
+
+func TestNumRecords_index(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dcsv_index")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	for i, c := range indexCases {
+		schema := ddataset.NewSchema(c.fieldNames)
+		ds := NewWithOptions(c.filename, c.hasHeader, c.separator, schema, c.options)
+		want := ds.NumRecords()
+		for _, interval := range []int{0, 1, 3} {
+			options := c.options
+			options.IndexFilename = filepath.Join(tmpDir, "index.json")
+			options.IndexInterval = interval
+			ds := NewWithOptions(c.filename, c.hasHeader, c.separator, schema, options)
+			for j := 0; j < 2; j++ {
+				if got := ds.NumRecords(); got != want {
+					t.Errorf("(%d) NumRecords - interval: %d, got: %d, want: %d",
+						i, interval, got, want)
+				}
+			}
+			if _, err := os.Stat(options.IndexFilename); err != nil {
+				t.Errorf("(%d) Stat: %s", i, err)
+			}
+		}
+	}
+}
+
+func TestSeekRecord(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dcsv_index")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	for i, c := range indexCases {
+		schema := ddataset.NewSchema(c.fieldNames)
+		ds := NewWithOptions(c.filename, c.hasHeader, c.separator, schema, c.options)
+		want, err := readAllRows(ds)
+		if err != nil {
+			t.Fatalf("(%d) readAllRows: %s", i, err)
+		}
+		numRecords := int64(len(want.records))
+		for _, workers := range []int{0, 3} {
+			options := c.options
+			options.IndexFilename = filepath.Join(tmpDir, "index.json")
+			options.IndexInterval = 3
+			options.Workers = workers
+			options.ChunkSize = 16
+			ds := NewWithOptions(c.filename, c.hasHeader, c.separator, schema, options)
+			for _, n := range []int64{0, 1, 2, 3, 4, numRecords / 2, numRecords - 1} {
+				if n < 0 || n >= numRecords {
+					continue
+				}
+				got, err := readRowsAfterSeek(ds, n)
+				if err != nil {
+					t.Fatalf("(%d) readRowsAfterSeek: %s", i, err)
+				}
+				if len(got.records) != len(want.records[n:]) {
+					t.Errorf("(%d) workers: %d, n: %d, got %d records, want: %d",
+						i, workers, n, len(got.records), len(want.records[n:]))
+					continue
+				}
+				for j, record := range want.records[n:] {
+					if !testhelpers.MatchRecords(got.records[j], record) {
+						t.Errorf("(%d) workers: %d, n: %d, got: %s, want: %s",
+							i, workers, n, got.records[j], record)
+					}
+				}
+				if !reflect.DeepEqual(got.err, want.err) {
+					t.Errorf("(%d) workers: %d, n: %d, Err - got: %v, want: %v",
+						i, workers, n, got.err, want.err)
+				}
+				wantErrs := []*ddataset.RecordError{}
+				for j, err := range want.errs {
+					if want.errsAt[j] >= n {
+						wantErrs = append(wantErrs, err)
+					}
+				}
+				if !reflect.DeepEqual(got.errs, wantErrs) {
+					t.Errorf("(%d) workers: %d, n: %d, OnMalformed - got: %v, want: %v",
+						i, workers, n, got.errs, wantErrs)
+				}
+			}
+		}
+	}
+}
+
+func TestSeekRecord_errors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dcsv_index")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	indexFilename := filepath.Join(tmpDir, "index.json")
+	bankFilename := filepath.Join("fixtures", "bank.csv")
+	bankGzFilename := filepath.Join("fixtures", "bank.csv.gz")
+	utf16Filename := filepath.Join("fixtures", "utf16le.csv")
+	fieldNames := []string{
+		"age", "job", "marital", "education", "default", "balance",
+		"housing", "loan", "contact", "day", "month", "duration", "campaign",
+		"pdays", "previous", "poutcome", "y",
+	}
+	cases := []struct {
+		filename string
+		options  Options
+		n        int64
+		wantErr  error
+	}{
+		{filename: bankFilename,
+			options: Options{},
+			n:       0,
+			wantErr: errors.New("can't seek without an index: " + bankFilename),
+		},
+		{filename: bankFilename,
+			options: Options{IndexFilename: indexFilename},
+			n:       -1,
+			wantErr: errors.New("record doesn't exist: " + bankFilename +
+				", record: -1"),
+		},
+		{filename: bankFilename,
+			options: Options{IndexFilename: indexFilename},
+			n:       9,
+			wantErr: errors.New("record doesn't exist: " + bankFilename +
+				", record: 9"),
+		},
+		{filename: bankGzFilename,
+			options: Options{IndexFilename: indexFilename},
+			n:       0,
+			wantErr: errors.New("can't index compressed file: " + bankGzFilename),
+		},
+		{filename: utf16Filename,
+			options: Options{IndexFilename: indexFilename},
+			n:       0,
+			wantErr: errors.New("can't index file which isn't UTF-8: " +
+				utf16Filename + ", encoding: UTF-16LE"),
+		},
+	}
+	invalidFilename := filepath.Join("fixtures", "invalid_numfields_at_102.csv")
+	ds := NewWithOptions(
+		invalidFilename,
+		false,
+		',',
+		ddataset.NewSchema([]string{"band", "score", "team", "points", "level"}),
+		Options{IndexFilename: indexFilename},
+	)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	wantErr := &ddataset.RecordError{
+		Source:    invalidFilename,
+		Record:    102,
+		Line:      102,
+		NumFields: 5,
+		NumValues: 4,
+		Err:       csv.ErrFieldCount,
+	}
+	if err := conn.(*DCSVConn).SeekRecord(0); !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("SeekRecord - got: %v, want: %s", err, wantErr)
+	}
+	conn.Close()
+
+	for i, c := range cases {
+		ds := NewWithOptions(
+			c.filename,
+			true,
+			';',
+			ddataset.NewSchema(fieldNames),
+			c.options,
+		)
+		conn, err := ds.Open()
+		if err != nil {
+			t.Fatalf("(%d) Open: %s", i, err)
+		}
+		err = conn.(*DCSVConn).SeekRecord(c.n)
+		if !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) SeekRecord - got: %v, want: %s", i, err, c.wantErr)
+		}
+		conn.Close()
+	}
+}
+
+func TestNumRecords_indexInvalidated(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dcsv_index")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	filename := filepath.Join(tmpDir, "people.csv")
+	indexFilename := filepath.Join(tmpDir, "people.csv.idx")
+	writeFile := func(content string, modTime time.Time) {
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatalf("Chtimes: %s", err)
+		}
+	}
+	schema := ddataset.NewSchema([]string{"name", "age"})
+	options := Options{IndexFilename: indexFilename, IndexInterval: 2}
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		content string
+		modTime time.Time
+		want    int64
+	}{
+		{content: "name,age\nFred,32\nBob,41\n",
+			modTime: modTime,
+			want:    2,
+		},
+		// Size changed
+		{content: "name,age\nFred,32\nBob,41\nMary,29\n",
+			modTime: modTime,
+			want:    3,
+		},
+		// Only the modification time changed
+		{content: "name,age\nFred,32\nBo,41\nSu,2\nA,1\n",
+			modTime: modTime.Add(time.Second),
+			want:    4,
+		},
+		{content: "name,age\nFred,32\n\"Bob\nSmith\",41\nSu,2\n",
+			modTime: modTime.Add(2 * time.Second),
+			want:    3,
+		},
+	}
+	for i, c := range cases {
+		writeFile(c.content, c.modTime)
+		// A new Dataset is used for some cases to check that the index file
+		// is invalidated as well as the index held by the Dataset
+		for j := 0; j < 2; j++ {
+			ds := NewWithOptions(filename, true, ',', schema, options)
+			if got := ds.NumRecords(); got != c.want {
+				t.Errorf("(%d) NumRecords - got: %d, want: %d", i, got, c.want)
+			}
+		}
+	}
+}
+
+// readRowsAfterSeek opens a connection to ds, seeks to record n and then
+// reads the rest of the records
+func readRowsAfterSeek(ds ddataset.Dataset, n int64) (*parsedRows, error) {
+	rows := &parsedRows{errs: []*ddataset.RecordError{}}
+	d := ds.(*DCSV)
+	d.options.OnMalformed = func(err *ddataset.RecordError, raw string) {
+		rows.errs = append(rows.errs, err)
+	}
+	conn, err := ds.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.(*DCSVConn).SeekRecord(n); err != nil {
+		return nil, err
+	}
+	// Rows skipped while seeking aren't wanted
+	rows.errs = rows.errs[:0]
+	for conn.Next() {
+		rows.records = append(rows.records, conn.Read().Clone())
+	}
+	rows.err = conn.Err()
+	return rows, nil
+}
@@ -0,0 +1,289 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lawrencewoodman/ddataset"
+)
+
+// DCSVMulti represents a Dataset made from several CSV files which share
+// the same fields.  The records of each file are returned in turn in the
+// order the files were given.
+type DCSVMulti struct {
+	files      []*DCSV
+	fieldNames []string
+	schema     ddataset.Schema
+	options    Options
+	isReleased bool
+	// projectionErr is any error with Options.Fields
+	projectionErr error
+}
+
+// DCSVMultiConn represents a connection to a DCSVMulti Dataset.  Each
+// file is only opened once the records of the previous file have been
+// read.
+type DCSVMultiConn struct {
+	ctx        context.Context
+	dataset    *DCSVMulti
+	fileNum    int
+	conn       *DCSVConn
+	rejects    *os.File
+	numSkipped int64
+	// record is the current Record, which is kept once the connection
+	// to its file has been closed
+	record   ddataset.Record
+	err      error
+	isClosed bool
+}
+
+// NewMulti creates a new DCSVMulti Dataset from filenames.  Each file is
+// read using the same hasHeader, separator and options, so a header is
+// expected at the start of every file if hasHeader is true and is checked
+// using Options.HeaderMatch.  If Options.RejectsFilename is set then the
+// rejects from every file are written to it.  If Options.Fields is set then
+// only the fields it names are read from each file.  Options.IndexFilename
+// is ignored.
+func NewMulti(
+	filenames []string,
+	hasHeader bool,
+	separator rune,
+	schema ddataset.Schema,
+	options Options,
+) ddataset.Dataset {
+	fieldNames := schema.Names()
+	fileOptions := options
+	fileOptions.RejectsFilename = ""
+	fileOptions.IndexFilename = ""
+	files := make([]*DCSV, len(filenames))
+	for i, filename := range filenames {
+		files[i] = newDCSV(
+			filename,
+			hasHeader,
+			separator,
+			fieldNames,
+			schema,
+			fileOptions,
+		)
+	}
+	projection, _, projectionErr := projectSchema(schema, options.Fields)
+	return &DCSVMulti{
+		files:         files,
+		fieldNames:    projection.Names(),
+		schema:        projection,
+		options:       options,
+		isReleased:    false,
+		projectionErr: projectionErr,
+	}
+}
+
+// NewGlob creates a new DCSVMulti Dataset from the files matching pattern,
+// in lexical order, as described by NewMulti.  The pattern is matched when
+// NewGlob is called, so files created afterwards aren't included.  An
+// error is returned if the pattern is malformed or doesn't match any files.
+func NewGlob(
+	pattern string,
+	hasHeader bool,
+	separator rune,
+	schema ddataset.Schema,
+	options Options,
+) (ddataset.Dataset, error) {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no files match pattern: %s", pattern)
+	}
+	return NewMulti(filenames, hasHeader, separator, schema, options), nil
+}
+
+// Open creates a connection to the Dataset
+func (d *DCSVMulti) Open() (ddataset.Conn, error) {
+	return d.OpenContext(context.Background())
+}
+
+// OpenContext creates a connection to the Dataset which stops
+// returning Records once ctx is done.  Only the first file is opened.
+func (d *DCSVMulti) OpenContext(ctx context.Context) (ddataset.Conn, error) {
+	if d.isReleased {
+		return nil, ddataset.ErrReleased
+	}
+	if d.projectionErr != nil {
+		return nil, d.projectionErr
+	}
+	c := &DCSVMultiConn{
+		ctx:        ctx,
+		dataset:    d,
+		fileNum:    0,
+		conn:       nil,
+		rejects:    nil,
+		numSkipped: 0,
+		record:     ddataset.Record{},
+		err:        nil,
+		isClosed:   false,
+	}
+	if d.options.SkipMalformed && d.options.RejectsFilename != "" {
+		rejects, err := os.Create(d.options.RejectsFilename)
+		if err != nil {
+			return nil, err
+		}
+		c.rejects = rejects
+	}
+	if len(d.files) > 0 {
+		if err := c.openFile(); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Fields returns the field names used by the Dataset.  If Options.Fields
+// is set then these are the fields it names.
+func (d *DCSVMulti) Fields() []string {
+	return d.fieldNames
+}
+
+// Schema returns the Schema of the Dataset.  If Options.Fields is set
+// then it only describes the fields it names.
+func (d *DCSVMulti) Schema() ddataset.Schema {
+	return d.schema
+}
+
+// NumRecords returns the number of records in the Dataset.  If there is
+// a problem getting the number of records it returns -1.  NOTE: The returned
+// value can change if the underlying Dataset changes.
+func (d *DCSVMulti) NumRecords() int64 {
+	if d.isReleased || d.projectionErr != nil {
+		return -1
+	}
+	numRecords := int64(0)
+	for _, file := range d.files {
+		n := file.countRecords()
+		if n < 0 {
+			return -1
+		}
+		numRecords += n
+	}
+	return numRecords
+}
+
+// Release releases any resources associated with the Dataset d,
+// rendering it unusable in the future.
+func (d *DCSVMulti) Release() error {
+	if !d.isReleased {
+		d.isReleased = true
+		return nil
+	}
+	return ddataset.ErrReleased
+}
+
+// Next returns whether there is a Record to be Read
+func (c *DCSVMultiConn) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	if c.isClosed {
+		c.err = ddataset.ErrConnClosed
+		return false
+	}
+	for c.conn != nil {
+		if c.conn.Next() {
+			c.record = c.conn.Read()
+			return true
+		}
+		if err := c.conn.Err(); err != nil {
+			c.numSkipped += c.conn.NumSkipped()
+			c.conn = nil
+			c.Close()
+			c.err = err
+			return false
+		}
+		c.numSkipped += c.conn.NumSkipped()
+		conn := c.conn
+		c.conn = nil
+		if err := conn.Close(); err != nil {
+			c.Close()
+			c.err = err
+			return false
+		}
+		if c.fileNum+1 >= len(c.dataset.files) {
+			return false
+		}
+		c.fileNum++
+		if err := c.openFile(); err != nil {
+			c.Close()
+			c.err = err
+			return false
+		}
+	}
+	return false
+}
+
+// Err returns any errors from the connection
+func (c *DCSVMultiConn) Err() error {
+	return c.err
+}
+
+// Read returns the current Record
+func (c *DCSVMultiConn) Read() ddataset.Record {
+	return c.record
+}
+
+// Close closes the connection
+func (c *DCSVMultiConn) Close() error {
+	if c.isClosed {
+		return nil
+	}
+	c.isClosed = true
+	var err error
+	if c.conn != nil {
+		err = c.conn.Close()
+		c.conn = nil
+	}
+	if c.rejects != nil {
+		if rejectsErr := c.rejects.Close(); err == nil {
+			err = rejectsErr
+		}
+		c.rejects = nil
+	}
+	return err
+}
+
+// Filename returns the name of the file currently being read
+func (c *DCSVMultiConn) Filename() string {
+	if len(c.dataset.files) == 0 {
+		return ""
+	}
+	return c.dataset.files[c.fileNum].filename
+}
+
+// NumSkipped returns the number of rows that have been skipped because
+// they were malformed when Options.SkipMalformed is set
+func (c *DCSVMultiConn) NumSkipped() int64 {
+	if c.conn != nil {
+		return c.numSkipped + c.conn.NumSkipped()
+	}
+	return c.numSkipped
+}
+
+// openFile opens the file at c.fileNum
+func (c *DCSVMultiConn) openFile() error {
+	file := c.dataset.files[c.fileNum]
+	if file.isReleased {
+		return ddataset.ErrReleased
+	}
+	conn, err := file.openConn(c.ctx, file.recordsRows())
+	if err != nil {
+		return err
+	}
+	conn.rejects = c.rejects
+	c.conn = conn
+	return nil
+}
@@ -0,0 +1,282 @@
+package dcsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
+)
+
+var salesSchema = ddataset.NewSchema([]string{"region", "month", "amount"})
+
+func salesRecord(region, month, amount string) ddataset.Record {
+	return ddataset.Record{
+		"region": dlit.NewString(region),
+		"month":  dlit.NewString(month),
+		"amount": dlit.NewString(amount),
+	}
+}
+
+func TestMultiRead(t *testing.T) {
+	filenames := []string{
+		filepath.Join("fixtures", "sales", "sales-2026-02.csv"),
+		filepath.Join("fixtures", "sales", "sales-2026-01.csv"),
+	}
+	wantRecords := []ddataset.Record{
+		salesRecord("north", "2026-02", "130"),
+		salesRecord("east", "2026-02", "92"),
+		salesRecord("west", "2026-02", "71"),
+		salesRecord("north", "2026-01", "120"),
+		salesRecord("south", "2026-01", "85"),
+	}
+	ds := NewMulti(
+		filenames,
+		true,
+		',',
+		salesSchema,
+		Options{HeaderMatch: HeaderExact},
+	)
+	if got := ds.NumRecords(); got != int64(len(wantRecords)) {
+		t.Errorf("NumRecords - got: %d, want: %d", got, len(wantRecords))
+	}
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	gotRecords := []ddataset.Record{}
+	gotFilenames := []string{}
+	for conn.Next() {
+		gotRecords = append(gotRecords, conn.Read().Clone())
+		gotFilenames = append(gotFilenames, conn.(*DCSVMultiConn).Filename())
+	}
+	if err := conn.Err(); err != nil {
+		t.Fatalf("Err: %s", err)
+	}
+	if len(gotRecords) != len(wantRecords) {
+		t.Fatalf("Read - got %d records, want: %d",
+			len(gotRecords), len(wantRecords))
+	}
+	for i, wantRecord := range wantRecords {
+		if !testhelpers.MatchRecords(gotRecords[i], wantRecord) {
+			t.Errorf("Read (%d) - got: %s, want: %s", i, gotRecords[i], wantRecord)
+		}
+	}
+	wantFilenames := []string{
+		filenames[0], filenames[0], filenames[0], filenames[1], filenames[1],
+	}
+	if !reflect.DeepEqual(gotFilenames, wantFilenames) {
+		t.Errorf("Filename - got: %v, want: %v", gotFilenames, wantFilenames)
+	}
+	if conn.Next() {
+		t.Errorf("Next - got: true, want: false")
+	}
+	lastRecord := wantRecords[len(wantRecords)-1]
+	if got := conn.Read(); !testhelpers.MatchRecords(got, lastRecord) {
+		t.Errorf("Read after end - got: %s, want: %s", got, lastRecord)
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close: %s", err)
+	}
+	if got := conn.Read(); !testhelpers.MatchRecords(got, lastRecord) {
+		t.Errorf("Read after Close - got: %s, want: %s", got, lastRecord)
+	}
+}
+
+func TestMultiRead_empty(t *testing.T) {
+	ds := NewMulti([]string{}, true, ',', salesSchema, Options{})
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	if conn.Next() {
+		t.Errorf("Next - got: true, want: false")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err: %s", err)
+	}
+	if got := conn.Read(); len(got) != 0 {
+		t.Errorf("Read - got: %s, want: empty record", got)
+	}
+}
+
+func TestNewGlob(t *testing.T) {
+	pattern := filepath.Join("fixtures", "sales", "sales-*.csv")
+	ds, err := NewGlob(pattern, true, ',', salesSchema, Options{})
+	if err != nil {
+		t.Fatalf("NewGlob: %s", err)
+	}
+	wantDS := NewMulti(
+		[]string{
+			filepath.Join("fixtures", "sales", "sales-2026-01.csv"),
+			filepath.Join("fixtures", "sales", "sales-2026-02.csv"),
+		},
+		true,
+		',',
+		salesSchema,
+		Options{},
+	)
+	if err := testhelpers.CheckDatasetsEqual(ds, wantDS); err != nil {
+		t.Errorf("checkDatasetsEqual: err: %s", err)
+	}
+}
+
+func TestNewGlob_errors(t *testing.T) {
+	cases := []struct {
+		pattern string
+		wantErr error
+	}{
+		{pattern: filepath.Join("fixtures", "nothing-*.csv"),
+			wantErr: errors.New("no files match pattern: " +
+				filepath.Join("fixtures", "nothing-*.csv")),
+		},
+		{pattern: "[",
+			wantErr: filepath.ErrBadPattern,
+		},
+	}
+	for i, c := range cases {
+		_, err := NewGlob(c.pattern, true, ',', salesSchema, Options{})
+		if !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) NewGlob - got: %v, want: %s", i, err, c.wantErr)
+		}
+	}
+}
+
+func TestMultiErr(t *testing.T) {
+	badFilename := filepath.Join("fixtures", "sales-bad-2026-03.csv")
+	filenames := []string{
+		filepath.Join("fixtures", "sales", "sales-2026-01.csv"),
+		badFilename,
+	}
+	wantErr := &ddataset.RecordError{
+		Source:    badFilename,
+		Record:    2,
+		Line:      3,
+		NumFields: 3,
+		NumValues: 2,
+		Err:       csv.ErrFieldCount,
+	}
+	ds := NewMulti(filenames, true, ',', salesSchema, Options{})
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	numRecords := 0
+	for conn.Next() {
+		numRecords++
+	}
+	if numRecords != 3 {
+		t.Errorf("Next - got %d records, want: 3", numRecords)
+	}
+	if err := conn.Err(); !reflect.DeepEqual(err, wantErr) {
+		t.Errorf("Err - got: %v, want: %s", err, wantErr)
+	}
+	if conn.Next() {
+		t.Errorf("Next - got: true, want: false")
+	}
+}
+
+func TestMultiErr_missingFile(t *testing.T) {
+	missingFilename := filepath.Join("fixtures", "sales-missing.csv")
+	wantPathErr := &os.PathError{
+		Op:   "open",
+		Path: missingFilename,
+		Err:  syscall.ENOENT,
+	}
+	// The missing file is first so Open fails
+	ds := NewMulti([]string{missingFilename}, true, ',', salesSchema, Options{})
+	if _, err := ds.Open(); err == nil {
+		t.Errorf("Open - got: nil, want: %s", wantPathErr)
+	} else if err := testhelpers.CheckPathErrorMatch(err, wantPathErr); err != nil {
+		t.Errorf("Open: %s", err)
+	}
+
+	// The missing file is second so it isn't opened until the records
+	// of the first file have been read
+	filenames := []string{
+		filepath.Join("fixtures", "sales", "sales-2026-01.csv"),
+		missingFilename,
+	}
+	ds = NewMulti(filenames, true, ',', salesSchema, Options{})
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	numRecords := 0
+	for conn.Next() {
+		numRecords++
+	}
+	if numRecords != 2 {
+		t.Errorf("Next - got %d records, want: 2", numRecords)
+	}
+	if err := testhelpers.CheckPathErrorMatch(conn.Err(), wantPathErr); err != nil {
+		t.Errorf("Err: %s", err)
+	}
+}
+
+func TestMultiRead_skipMalformed(t *testing.T) {
+	filenames := []string{
+		filepath.Join("fixtures", "sales-bad-2026-03.csv"),
+		filepath.Join("fixtures", "sales", "sales-2026-01.csv"),
+		filepath.Join("fixtures", "sales-bad-2026-03.csv"),
+	}
+	gotSources := []string{}
+	ds := NewMulti(filenames, true, ',', salesSchema, Options{
+		SkipMalformed: true,
+		OnMalformed: func(err *ddataset.RecordError, raw string) {
+			gotSources = append(gotSources, err.Source)
+		},
+	})
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	numRecords := 0
+	for conn.Next() {
+		numRecords++
+	}
+	if err := conn.Err(); err != nil {
+		t.Fatalf("Err: %s", err)
+	}
+	if numRecords != 4 {
+		t.Errorf("Next - got %d records, want: 4", numRecords)
+	}
+	if got := conn.(*DCSVMultiConn).NumSkipped(); got != 2 {
+		t.Errorf("NumSkipped - got: %d, want: 2", got)
+	}
+	wantSources := []string{filenames[0], filenames[2]}
+	if !reflect.DeepEqual(gotSources, wantSources) {
+		t.Errorf("OnMalformed - got: %v, want: %v", gotSources, wantSources)
+	}
+	if got := ds.NumRecords(); got != 4 {
+		t.Errorf("NumRecords - got: %d, want: 4", got)
+	}
+	if !reflect.DeepEqual(gotSources, wantSources) {
+		t.Errorf("OnMalformed after NumRecords - got: %v, want: %v",
+			gotSources, wantSources)
+	}
+}
+
+func TestMultiOpen_released(t *testing.T) {
+	ds := NewMulti([]string{}, true, ',', salesSchema, Options{})
+	if err := ds.Release(); err != nil {
+		t.Fatalf("Release: %s", err)
+	}
+	if _, err := ds.Open(); err != ddataset.ErrReleased {
+		t.Errorf("Open - got: %v, want: %s", err, ddataset.ErrReleased)
+	}
+	if err := ds.Release(); err != ddataset.ErrReleased {
+		t.Errorf("Release - got: %v, want: %s", err, ddataset.ErrReleased)
+	}
+}
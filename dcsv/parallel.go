@@ -0,0 +1,453 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultChunkSize is the size of the chunks a file is split into if
+// Options.ChunkSize isn't set
+const defaultChunkSize = 1 << 20
+
+// chunkReader reads the rows of a CSV file by splitting it into chunks
+// which end at record boundaries and parsing the chunks in parallel.  The
+// rows are returned in the order they appear in the file.
+type chunkReader struct {
+	dataset *DCSV
+	// results holds the channel that each chunk's parsedChunk will be
+	// sent on in the order the chunks were split from the file
+	results   chan chan *parsedChunk
+	done      chan struct{}
+	wg        sync.WaitGroup
+	current   *parsedChunk
+	rowNum    int
+	row       *parsedRow
+	chunkSize int
+}
+
+// chunk is part of a CSV file which starts and ends at record boundaries
+type chunk struct {
+	data []byte
+	// startLine is the line number of the start of data
+	startLine       int
+	fieldsPerRecord int
+	result          chan *parsedChunk
+}
+
+// parsedChunk holds the rows parsed from a chunk or an error reading the
+// file
+type parsedChunk struct {
+	data []byte
+	rows []parsedRow
+	err  error
+}
+
+// parsedRow is a row parsed from a chunk along with its position
+type parsedRow struct {
+	fields []string
+	line   int
+	start  int64
+	end    int64
+	err    error
+}
+
+// newChunkReader starts splitting src into chunks and parsing them with
+// Options.Workers goroutines.  startLine is the line number of the start
+// of src.  The first chunk is parsed using firstFieldsPerRecord as
+// csv.Reader.FieldsPerRecord and the rest using fieldsPerRecord.
+func newChunkReader(
+	d *DCSV,
+	src io.Reader,
+	startLine int,
+	firstFieldsPerRecord int,
+	fieldsPerRecord int,
+) *chunkReader {
+	chunkSize := d.options.ChunkSize
+	if chunkSize < 1 {
+		chunkSize = defaultChunkSize
+	}
+	cr := &chunkReader{
+		dataset:   d,
+		results:   make(chan chan *parsedChunk, d.options.Workers*2),
+		done:      make(chan struct{}),
+		current:   nil,
+		rowNum:    0,
+		row:       nil,
+		chunkSize: chunkSize,
+	}
+	jobs := make(chan *chunk, d.options.Workers)
+	cr.wg.Add(d.options.Workers + 1)
+	go cr.split(src, startLine, firstFieldsPerRecord, fieldsPerRecord, jobs)
+	for i := 0; i < d.options.Workers; i++ {
+		go cr.work(jobs)
+	}
+	return cr
+}
+
+// chunkFieldsPerRecord returns the csv.Reader.FieldsPerRecord to use
+// for the first chunk and the rest of the chunks of a file with header,
+// so that the number of fields is checked in the same way as a csv.Reader
+// reading the whole file would
+func (d *DCSV) chunkFieldsPerRecord(header []string) (int, int) {
+	switch {
+	case d.options.SkipMalformed:
+		return -1, -1
+	case d.hasHeader:
+		return len(header), len(header)
+	}
+	return 0, d.numFields
+}
+
+// read returns the next row and any error parsing it.  It returns
+// io.EOF once there are no more rows.
+func (cr *chunkReader) read() ([]string, error) {
+	for cr.current == nil || cr.rowNum >= len(cr.current.rows) {
+		result, ok := <-cr.results
+		if !ok {
+			return nil, io.EOF
+		}
+		cr.current = <-result
+		cr.rowNum = 0
+		if cr.current.err != nil {
+			return nil, cr.current.err
+		}
+	}
+	cr.row = &cr.current.rows[cr.rowNum]
+	cr.rowNum++
+	return cr.row.fields, cr.row.err
+}
+
+// line returns the line number of the start of the current row
+func (cr *chunkReader) line() int {
+	return cr.row.line
+}
+
+// raw returns the raw text of the current row
+func (cr *chunkReader) raw() []byte {
+	return cr.current.data[cr.row.start:cr.row.end]
+}
+
+// close stops splitting and parsing the file and waits for the
+// goroutines to finish.  The file must be closed first so that the
+// splitter can't be left waiting for a read which never finishes.
+func (cr *chunkReader) close() {
+	close(cr.done)
+	cr.wg.Wait()
+}
+
+// split reads src and sends it in chunks to be parsed by the workers
+func (cr *chunkReader) split(
+	src io.Reader,
+	startLine int,
+	firstFieldsPerRecord int,
+	fieldsPerRecord int,
+	jobs chan<- *chunk,
+) {
+	defer cr.wg.Done()
+	defer close(cr.results)
+	defer close(jobs)
+	scanner := newBoundaryScanner(cr.dataset)
+	line := startLine
+	data := make([]byte, 0, cr.chunkSize)
+	// scanned is the number of bytes of data which have been scanned,
+	// boundary is the end of the last record found in data and numLines
+	// and boundaryLines are the number of lines in data up to those points
+	scanned, boundary, numLines, boundaryLines := 0, 0, 0, 0
+	isFirst := true
+	send := func(end int) bool {
+		c := &chunk{
+			data:            data[:end],
+			startLine:       line,
+			fieldsPerRecord: fieldsPerRecord,
+			result:          make(chan *parsedChunk, 1),
+		}
+		if isFirst {
+			c.fieldsPerRecord = firstFieldsPerRecord
+			isFirst = false
+		}
+		select {
+		case cr.results <- c.result:
+		case <-cr.done:
+			return false
+		}
+		select {
+		case jobs <- c:
+		case <-cr.done:
+			return false
+		}
+		rest := make([]byte, len(data)-end, len(data)-end+cr.chunkSize)
+		copy(rest, data[end:])
+		data = rest
+		line += boundaryLines
+		numLines -= boundaryLines
+		scanned -= end
+		boundary, boundaryLines = 0, 0
+		return true
+	}
+	for {
+		if len(data) == cap(data) {
+			data = append(data, 0)[:len(data)]
+		}
+		n, err := src.Read(data[len(data):cap(data)])
+		data = data[:len(data)+n]
+		for scanned < len(data) {
+			if n, lines := scanner.skip(data[scanned:]); n > 0 {
+				scanned += n
+				numLines += lines
+				continue
+			}
+			r, size := rune(data[scanned]), 1
+			if r >= utf8.RuneSelf {
+				if err == nil && !utf8.FullRune(data[scanned:]) {
+					// Wait for the rest of the rune to be read
+					break
+				}
+				r, size = utf8.DecodeRune(data[scanned:])
+			}
+			scanned += size
+			if r == '\n' {
+				numLines++
+			}
+			if scanner.scan(r) {
+				boundary, boundaryLines = scanned, numLines
+			}
+		}
+		if err == io.EOF {
+			if len(data) > 0 {
+				send(len(data))
+			}
+			return
+		}
+		if err != nil {
+			if boundary > 0 && !send(boundary) {
+				return
+			}
+			result := make(chan *parsedChunk, 1)
+			result <- &parsedChunk{err: err}
+			select {
+			case cr.results <- result:
+			case <-cr.done:
+			}
+			return
+		}
+		if len(data) >= cr.chunkSize && boundary > 0 && !send(boundary) {
+			return
+		}
+	}
+}
+
+// work parses the chunks sent on jobs until it is closed
+func (cr *chunkReader) work(jobs <-chan *chunk) {
+	defer cr.wg.Done()
+	for c := range jobs {
+		c.result <- cr.parse(c)
+	}
+}
+
+// parse parses the rows of c, adjusting the line numbers so that they
+// are relative to the start of the file
+func (cr *chunkReader) parse(c *chunk) *parsedChunk {
+	r := cr.dataset.newCsvReader(bytes.NewReader(c.data))
+	r.FieldsPerRecord = c.fieldsPerRecord
+	// Each row is kept until it is read so it can't be reused
+	r.ReuseRecord = false
+	lineOffset := c.startLine - 1
+	rows := []parsedRow{}
+	start := int64(0)
+	for {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		row := parsedRow{
+			fields: fields,
+			start:  start,
+			end:    r.InputOffset(),
+			err:    err,
+		}
+		if len(fields) > 0 {
+			line, _ := r.FieldPos(0)
+			row.line = line + lineOffset
+		}
+		if pe, ok := err.(*csv.ParseError); ok {
+			row.err = &csv.ParseError{
+				StartLine: pe.StartLine + lineOffset,
+				Line:      pe.Line + lineOffset,
+				Column:    pe.Column,
+				Err:       pe.Err,
+			}
+		}
+		rows = append(rows, row)
+		start = row.end
+	}
+	return &parsedChunk{data: c.data, rows: rows, err: nil}
+}
+
+type scanState int
+
+const (
+	scanFieldStart scanState = iota
+	scanUnquoted
+	scanQuoted
+	scanQuoteInQuoted
+	scanQuoteCR
+	scanComment
+	// scanStartCR is after a carriage return at the start of a record,
+	// which makes the line empty if a newline follows
+	scanStartCR
+	// scanError is after an error, where a csv.Reader skips the rest
+	// of the line
+	scanError
+)
+
+// boundaryScanner finds the ends of records in a CSV file in the same way
+// as a csv.Reader without parsing the fields.  This includes where a
+// csv.Reader would skip the rest of a line after an error.
+type boundaryScanner struct {
+	separator        rune
+	comment          rune
+	lazyQuotes       bool
+	trimLeadingSpace bool
+	state            scanState
+	recordStart      bool
+}
+
+func newBoundaryScanner(d *DCSV) *boundaryScanner {
+	return &boundaryScanner{
+		separator:        d.separator,
+		comment:          d.options.Comment,
+		lazyQuotes:       d.options.LazyQuotes,
+		trimLeadingSpace: d.options.TrimLeadingSpace,
+		state:            scanFieldStart,
+		recordStart:      true,
+	}
+}
+
+// scan returns whether r ends a record
+func (s *boundaryScanner) scan(r rune) bool {
+	switch s.state {
+	case scanFieldStart:
+		recordStart := s.recordStart
+		s.recordStart = false
+		switch {
+		case r == '\n' && recordStart:
+			// A csv.Reader skips empty lines, so they belong to the next
+			// record
+			s.recordStart = true
+		case r == '\n':
+			return s.endRecord()
+		case recordStart && r == '\r':
+			s.state = scanStartCR
+		case recordStart && s.comment != 0 && r == s.comment:
+			s.state = scanComment
+		case r == '"':
+			s.state = scanQuoted
+		case r == s.separator:
+		case s.trimLeadingSpace && unicode.IsSpace(r):
+		default:
+			s.state = scanUnquoted
+		}
+	case scanComment:
+		if r == '\n' {
+			// A csv.Reader skips comment lines, so they belong to the next
+			// record
+			s.state = scanFieldStart
+			s.recordStart = true
+		}
+	case scanStartCR:
+		if r == '\n' {
+			s.state = scanFieldStart
+			s.recordStart = true
+			return false
+		}
+		// The carriage return is part of the first field
+		s.state = scanUnquoted
+		if s.trimLeadingSpace {
+			s.state = scanFieldStart
+		}
+		return s.scan(r)
+	case scanUnquoted, scanError:
+		switch {
+		case r == '\n':
+			return s.endRecord()
+		case r == s.separator && s.state == scanUnquoted:
+			s.state = scanFieldStart
+		case r == '"' && s.state == scanUnquoted && !s.lazyQuotes:
+			// A bare quote in an unquoted field is an error
+			s.state = scanError
+		}
+	case scanQuoted:
+		if r == '"' {
+			s.state = scanQuoteInQuoted
+		}
+	case scanQuoteInQuoted:
+		switch {
+		case r == '"':
+			s.state = scanQuoted
+		case r == s.separator:
+			s.state = scanFieldStart
+		case r == '\n':
+			return s.endRecord()
+		case r == '\r':
+			s.state = scanQuoteCR
+		case s.lazyQuotes:
+			s.state = scanQuoted
+		default:
+			// An invalid quote is an error
+			s.state = scanError
+		}
+	case scanQuoteCR:
+		switch {
+		case r == '\n':
+			return s.endRecord()
+		case s.lazyQuotes:
+			s.state = scanQuoted
+			return s.scan(r)
+		default:
+			s.state = scanError
+		}
+	}
+	return false
+}
+
+// skip returns the number of bytes at the start of data which can't
+// change the state of the scanner and the number of lines in them.  This
+// avoids scanning each rune of the fields.
+func (s *boundaryScanner) skip(data []byte) (int, int) {
+	switch s.state {
+	case scanQuoted:
+		n := bytes.IndexByte(data, '"')
+		if n < 0 {
+			n = len(data)
+		}
+		return n, bytes.Count(data[:n], []byte{'\n'})
+	case scanUnquoted:
+		for n, b := range data {
+			if b == '\n' || b >= utf8.RuneSelf || rune(b) == s.separator ||
+				(b == '"' && !s.lazyQuotes) {
+				return n, 0
+			}
+		}
+		return len(data), 0
+	case scanComment, scanError:
+		n := bytes.IndexByte(data, '\n')
+		if n < 0 {
+			n = len(data)
+		}
+		return n, 0
+	}
+	return 0, 0
+}
+
+func (s *boundaryScanner) endRecord() bool {
+	s.state = scanFieldStart
+	s.recordStart = true
+	return true
+}
@@ -0,0 +1,379 @@
+package dcsv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+)
+
+func TestRead_parallel(t *testing.T) {
+	debtFields := []string{
+		"name",
+		"balance",
+		"numCards",
+		"martialStatus",
+		"tertiaryEducated",
+		"success",
+	}
+	bankFields := []string{
+		"age", "job", "marital", "education", "default", "balance",
+		"housing", "loan", "contact", "day", "month", "duration", "campaign",
+		"pdays", "previous", "poutcome", "y",
+	}
+	cases := []struct {
+		filename   string
+		hasHeader  bool
+		separator  rune
+		fieldNames []string
+		options    Options
+	}{
+		{filename: filepath.Join("fixtures", "debt.csv"),
+			hasHeader:  true,
+			separator:  ',',
+			fieldNames: debtFields,
+		},
+		{filename: filepath.Join("fixtures", "bank.csv"),
+			hasHeader:  true,
+			separator:  ';',
+			fieldNames: bankFields,
+			options:    Options{HeaderMatch: HeaderExact},
+		},
+		{filename: filepath.Join("fixtures", "bank.csv.gz"),
+			hasHeader:  true,
+			separator:  ';',
+			fieldNames: bankFields,
+		},
+		{filename: filepath.Join("fixtures", "bank.csv"),
+			hasHeader:  false,
+			separator:  ';',
+			fieldNames: bankFields,
+		},
+		{filename: filepath.Join("fixtures", "invalid_numfields_at_102.csv"),
+			hasHeader:  false,
+			separator:  ',',
+			fieldNames: []string{"band", "score", "team", "points", "level"},
+		},
+		{filename: filepath.Join("fixtures", "malformed.csv"),
+			hasHeader:  true,
+			separator:  ',',
+			fieldNames: []string{"name", "age", "dept"},
+			options:    Options{SkipMalformed: true},
+		},
+		{filename: filepath.Join("fixtures", "options.csv"),
+			hasHeader:  true,
+			separator:  ',',
+			fieldNames: []string{"name", "age", "dept"},
+			options: Options{
+				LazyQuotes:       true,
+				Comment:          '#',
+				TrimLeadingSpace: true,
+				ReuseRecord:      true,
+				SkipLines:        2,
+				NullToken:        "NA",
+			},
+		},
+		{filename: filepath.Join("fixtures", "quoted.csv"),
+			hasHeader:  true,
+			separator:  ',',
+			fieldNames: []string{"id", "name", "notes"},
+			options:    Options{Comment: '#'},
+		},
+		{filename: filepath.Join("fixtures", "quoted.csv"),
+			hasHeader:  true,
+			separator:  ',',
+			fieldNames: []string{"id", "name", "notes"},
+			options:    Options{Comment: '#', SkipMalformed: true},
+		},
+		{filename: filepath.Join("fixtures", "quoted.csv"),
+			hasHeader:  true,
+			separator:  ',',
+			fieldNames: []string{"id", "name", "notes"},
+			options:    Options{SkipMalformed: true},
+		},
+		{filename: filepath.Join("fixtures", "quoted.csv"),
+			hasHeader:  true,
+			separator:  ',',
+			fieldNames: []string{"id", "name", "notes"},
+			options:    Options{LazyQuotes: true, SkipMalformed: true},
+		},
+		{filename: filepath.Join("fixtures", "barequote.csv"),
+			hasHeader:  true,
+			separator:  ',',
+			fieldNames: []string{"x", "y"},
+			options:    Options{SkipMalformed: true},
+		},
+		{filename: filepath.Join("fixtures", "quoted.csv"),
+			hasHeader:  true,
+			separator:  'ö',
+			fieldNames: []string{"id,name,notes"},
+			options:    Options{SkipMalformed: true},
+		},
+	}
+	for i, c := range cases {
+		schema := ddataset.NewSchema(c.fieldNames)
+		ds := NewWithOptions(c.filename, c.hasHeader, c.separator, schema, c.options)
+		want, err := readAllRows(ds)
+		if err != nil {
+			t.Fatalf("(%d) readAllRows: %s", i, err)
+		}
+		for _, workers := range []int{2, 5} {
+			for _, chunkSize := range []int{0, 1, 7, 100} {
+				options := c.options
+				options.Workers = workers
+				options.ChunkSize = chunkSize
+				ds := NewWithOptions(
+					c.filename,
+					c.hasHeader,
+					c.separator,
+					schema,
+					options,
+				)
+				got, err := readAllRows(ds)
+				if err != nil {
+					t.Fatalf("(%d) readAllRows: %s", i, err)
+				}
+				if err := checkRowsEqual(got, want); err != nil {
+					t.Errorf("(%d) workers: %d, chunkSize: %d, %s",
+						i, workers, chunkSize, err)
+				}
+			}
+		}
+	}
+}
+
+func TestRead_parallel_random(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dcsv_parallel")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	filename := filepath.Join(tmpDir, "random.csv")
+	// The pieces are chosen to exercise quoting, comments, blank lines,
+	// line endings and multi-byte runes
+	pieces := []string{
+		"a", "bc", ",", ",", "\"", "\"", "\"\"", "\n", "\n", "\r\n", "\r",
+		" ", "#", "é", ";",
+	}
+	numCases := 500
+	if testing.Short() {
+		numCases = 100
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < numCases; i++ {
+		var sb strings.Builder
+		numPieces := rnd.Intn(60)
+		for j := 0; j < numPieces; j++ {
+			sb.WriteString(pieces[rnd.Intn(len(pieces))])
+		}
+		data := sb.String()
+		if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+		options := Options{
+			LazyQuotes:       rnd.Intn(2) == 0,
+			TrimLeadingSpace: rnd.Intn(2) == 0,
+			SkipMalformed:    rnd.Intn(4) != 0,
+		}
+		if rnd.Intn(2) == 0 {
+			options.Comment = '#'
+		}
+		separator := ','
+		if rnd.Intn(4) == 0 {
+			separator = 'é'
+		}
+		hasHeader := rnd.Intn(2) == 0
+		schema := ddataset.NewSchema([]string{"a", "b"})
+		ds := NewWithOptions(filename, hasHeader, separator, schema, options)
+		want, err := readAllRows(ds)
+		if err != nil {
+			// The header can't be read so there is nothing to compare
+			continue
+		}
+		for _, chunkSize := range []int{1, 5, 16} {
+			options := options
+			options.Workers = 3
+			options.ChunkSize = chunkSize
+			ds := NewWithOptions(filename, hasHeader, separator, schema, options)
+			got, err := readAllRows(ds)
+			if err != nil {
+				t.Fatalf("(%d) readAllRows: %s", i, err)
+			}
+			if err := checkRowsEqual(got, want); err != nil {
+				t.Errorf("(%d) data: %q, options: %+v, separator: %q, "+
+					"hasHeader: %t, chunkSize: %d, %s",
+					i, data, options, separator, hasHeader, chunkSize, err)
+			}
+		}
+	}
+}
+
+func TestClose_parallel(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	fieldNames := []string{
+		"name",
+		"balance",
+		"numCards",
+		"martialStatus",
+		"tertiaryEducated",
+		"success",
+	}
+	ds := NewWithOptions(
+		filename,
+		true,
+		',',
+		ddataset.NewSchema(fieldNames),
+		Options{Workers: 4, ChunkSize: 64},
+	)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	for i := 0; i < 10; i++ {
+		if !conn.Next() {
+			t.Fatalf("Next - return false early, err: %v", conn.Err())
+		}
+	}
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close: %s", err)
+	}
+	if conn.Next() {
+		t.Errorf("Next - got: true, want: false")
+	}
+	if err := conn.Err(); err != ddataset.ErrConnClosed {
+		t.Errorf("Err - got: %v, want: %s", err, ddataset.ErrConnClosed)
+	}
+}
+
+func TestClose_parallel_blocked(t *testing.T) {
+	// The pipe is never closed by the writer so the chunkReader is left
+	// waiting to read more
+	pr, pw := io.Pipe()
+	go pw.Write([]byte("name,age\nFred,32\nBob,41\n"))
+	numOpens := 0
+	open := func() (io.ReadCloser, error) {
+		numOpens++
+		if numOpens > 1 {
+			return nil, errors.New("pipe already opened")
+		}
+		return pr, nil
+	}
+	ds := NewFromReader(
+		"pipe",
+		open,
+		true,
+		',',
+		ddataset.NewSchema([]string{"name", "age"}),
+		Options{Workers: 2, ChunkSize: 1},
+	)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if !conn.Next() {
+		t.Fatalf("Next - return false early, err: %v", conn.Err())
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- conn.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Close - didn't return")
+	}
+}
+
+func TestOpenContext_parallel(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.csv")
+	fieldNames := []string{
+		"name",
+		"balance",
+		"numCards",
+		"martialStatus",
+		"tertiaryEducated",
+		"success",
+	}
+	ds := NewWithOptions(
+		filename,
+		true,
+		',',
+		ddataset.NewSchema(fieldNames),
+		Options{Workers: 4, ChunkSize: 64},
+	)
+	if err := testhelpers.CheckOpenContextCancel(ds, 50); err != nil {
+		t.Errorf("CheckOpenContextCancel: %s", err)
+	}
+}
+
+// parsedRows holds everything a connection returns
+type parsedRows struct {
+	records []ddataset.Record
+	rejects []string
+	errs    []*ddataset.RecordError
+	// errsAt is the number of records read before each of errs
+	errsAt     []int64
+	numSkipped int64
+	err        error
+}
+
+func readAllRows(ds ddataset.Dataset) (*parsedRows, error) {
+	rows := &parsedRows{}
+	d := ds.(*DCSV)
+	d.options.OnMalformed = func(err *ddataset.RecordError, raw string) {
+		rows.errs = append(rows.errs, err)
+		rows.errsAt = append(rows.errsAt, int64(len(rows.records)))
+		rows.rejects = append(rows.rejects, raw)
+	}
+	conn, err := ds.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	for conn.Next() {
+		rows.records = append(rows.records, conn.Read().Clone())
+	}
+	rows.err = conn.Err()
+	rows.numSkipped = conn.(*DCSVConn).NumSkipped()
+	return rows, nil
+}
+
+func checkRowsEqual(got, want *parsedRows) error {
+	if len(got.records) != len(want.records) {
+		return fmt.Errorf("got %d records, want: %d",
+			len(got.records), len(want.records))
+	}
+	for i, record := range want.records {
+		if !testhelpers.MatchRecords(got.records[i], record) {
+			return fmt.Errorf("record: %d, got: %s, want: %s",
+				i, got.records[i], record)
+		}
+	}
+	if !reflect.DeepEqual(got.err, want.err) {
+		return fmt.Errorf("Err - got: %v, want: %v", got.err, want.err)
+	}
+	if got.numSkipped != want.numSkipped {
+		return fmt.Errorf("NumSkipped - got: %d, want: %d",
+			got.numSkipped, want.numSkipped)
+	}
+	if !reflect.DeepEqual(got.errs, want.errs) {
+		return fmt.Errorf("OnMalformed errs - got: %v, want: %v",
+			got.errs, want.errs)
+	}
+	if !reflect.DeepEqual(got.rejects, want.rejects) {
+		return fmt.Errorf("OnMalformed raw - got: %q, want: %q",
+			got.rejects, want.rejects)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"bytes"
+	"io"
+)
+
+// rowRecorder keeps the bytes read through it which haven't yet been
+// discarded, so that the raw text of a row can be recovered using the
+// offsets reported by csv.Reader.InputOffset
+type rowRecorder struct {
+	r     io.Reader
+	buf   []byte
+	start int64
+	// numLines is the number of lines in the discarded bytes
+	numLines int
+}
+
+func newRowRecorder(r io.Reader) *rowRecorder {
+	return &rowRecorder{r: r, buf: []byte{}, start: 0, numLines: 0}
+}
+
+func (rr *rowRecorder) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+// take returns a copy of the bytes up to offset end and discards them
+func (rr *rowRecorder) take(end int64) []byte {
+	n := end - rr.start
+	raw := make([]byte, n)
+	copy(raw, rr.buf[:n])
+	rr.discard(end)
+	return raw
+}
+
+// discard discards the bytes up to offset end
+func (rr *rowRecorder) discard(end int64) {
+	rr.numLines += bytes.Count(rr.buf[:end-rr.start], []byte{'\n'})
+	rr.buf = rr.buf[end-rr.start:]
+	rr.start = end
+}
+
+// rest returns a reader for the bytes which haven't been discarded
+// followed by the rest of the underlying reader
+func (rr *rowRecorder) rest() io.Reader {
+	return io.MultiReader(bytes.NewReader(rr.buf), rr.r)
+}
@@ -0,0 +1,54 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dcsv
+
+import (
+	"io"
+	"io/fs"
+
+	"github.com/lawrencewoodman/ddataset"
+)
+
+// NewFromFS creates a new DCSV Dataset from the file called name in fsys,
+// such as an embed.FS, whose fields are described by schema and which is
+// configured by options.  Each connection opens the file again.  The file
+// can only be indexed using Options.IndexFilename if fsys returns its size
+// and modification time and it can only be seeked if the fs.File returned
+// by fsys implements io.Seeker.
+func NewFromFS(
+	fsys fs.FS,
+	name string,
+	hasHeader bool,
+	separator rune,
+	schema ddataset.Schema,
+	options Options,
+) ddataset.Dataset {
+	d := newDCSV(name, hasHeader, separator, schema.Names(), schema, options)
+	d.open = func() (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}
+	d.stat = func() (fs.FileInfo, error) {
+		return fs.Stat(fsys, name)
+	}
+	return d
+}
+
+// NewFromReader creates a new DCSV Dataset whose fields are described by
+// schema and which is configured by options.  Each connection reads from
+// a new stream returned by open, which must return the same data each
+// time.  The stream is closed when the connection is closed.  name is used
+// in errors to identify the stream.  Options.IndexFilename can't be used.
+func NewFromReader(
+	name string,
+	open func() (io.ReadCloser, error),
+	hasHeader bool,
+	separator rune,
+	schema ddataset.Schema,
+	options Options,
+) ddataset.Dataset {
+	d := newDCSV(name, hasHeader, separator, schema.Names(), schema, options)
+	d.open = open
+	d.stat = nil
+	return d
+}
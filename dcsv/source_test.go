@@ -0,0 +1,258 @@
+package dcsv
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
+)
+
+//go:embed fixtures/sales
+var salesFS embed.FS
+
+var peopleCSV = "name,age\nFred,32\nBob,41\nMary,29\n"
+
+var peopleSchema = ddataset.NewSchema([]string{"name", "age"})
+
+var peopleRecords = []ddataset.Record{
+	ddataset.Record{"name": dlit.NewString("Fred"), "age": dlit.NewString("32")},
+	ddataset.Record{"name": dlit.NewString("Bob"), "age": dlit.NewString("41")},
+	ddataset.Record{"name": dlit.NewString("Mary"), "age": dlit.NewString("29")},
+}
+
+func TestNewFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/people.csv": &fstest.MapFile{Data: []byte(peopleCSV)},
+	}
+	ds := NewFromFS(fsys, "data/people.csv", true, ',', peopleSchema, Options{})
+	if got := ds.NumRecords(); got != 3 {
+		t.Errorf("NumRecords - got: %d, want: 3", got)
+	}
+	if err := checkConnsIndependent(ds, peopleRecords); err != nil {
+		t.Errorf("checkConnsIndependent: %s", err)
+	}
+}
+
+func TestNewFromFS_embed(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dcsv_source")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	name := "fixtures/sales/sales-2026-02.csv"
+	options := Options{
+		IndexFilename: filepath.Join(tmpDir, "index.json"),
+		IndexInterval: 2,
+	}
+	ds := NewFromFS(salesFS, name, true, ',', salesSchema, options)
+	if got := ds.NumRecords(); got != 3 {
+		t.Errorf("NumRecords - got: %d, want: 3", got)
+	}
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	if err := conn.(*DCSVConn).SeekRecord(2); err != nil {
+		t.Fatalf("SeekRecord: %s", err)
+	}
+	if !conn.Next() {
+		t.Fatalf("Next - return false early, err: %v", conn.Err())
+	}
+	want := salesRecord("west", "2026-02", "71")
+	if got := conn.Read(); !testhelpers.MatchRecords(got, want) {
+		t.Errorf("Read - got: %s, want: %s", got, want)
+	}
+}
+
+func TestNewFromFS_errors(t *testing.T) {
+	fsys := os.DirFS("fixtures")
+	ds := NewFromFS(fsys, "missing.csv", true, ',', peopleSchema, Options{})
+	_, err := ds.Open()
+	wantPathErr := &os.PathError{
+		Op:   "open",
+		Path: "missing.csv",
+		Err:  syscall.ENOENT,
+	}
+	if err := testhelpers.CheckPathErrorMatch(err, wantPathErr); err != nil {
+		t.Errorf("Open: %s", err)
+	}
+}
+
+func TestNewFromReader(t *testing.T) {
+	numOpens := 0
+	open := func() (io.ReadCloser, error) {
+		numOpens++
+		return ioutil.NopCloser(bytes.NewReader([]byte(peopleCSV))), nil
+	}
+	ds := NewFromReader("people", open, true, ',', peopleSchema, Options{})
+	if got := ds.NumRecords(); got != 3 {
+		t.Errorf("NumRecords - got: %d, want: 3", got)
+	}
+	if err := checkConnsIndependent(ds, peopleRecords); err != nil {
+		t.Errorf("checkConnsIndependent: %s", err)
+	}
+	if numOpens != 3 {
+		t.Errorf("open called %d times, want: 3", numOpens)
+	}
+}
+
+func TestNewFromReader_compressed(t *testing.T) {
+	filename := filepath.Join("fixtures", "bank.csv.gz")
+	compressed, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	open := func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	fieldNames := []string{
+		"age", "job", "marital", "education", "default", "balance",
+		"housing", "loan", "contact", "day", "month", "duration", "campaign",
+		"pdays", "previous", "poutcome", "y",
+	}
+	ds := NewFromReader(
+		"bank",
+		open,
+		true,
+		';',
+		ddataset.NewSchema(fieldNames),
+		Options{},
+	)
+	wantDS := New(filepath.Join("fixtures", "bank.csv"), true, ';', fieldNames)
+	if err := testhelpers.CheckDatasetsEqual(ds, wantDS); err != nil {
+		t.Errorf("checkDatasetsEqual: err: %s", err)
+	}
+}
+
+func TestNewFromReader_errors(t *testing.T) {
+	openErr := errors.New("can't connect")
+	ds := NewFromReader(
+		"people",
+		func() (io.ReadCloser, error) { return nil, openErr },
+		true,
+		',',
+		peopleSchema,
+		Options{},
+	)
+	if _, err := ds.Open(); err != openErr {
+		t.Errorf("Open - got: %v, want: %s", err, openErr)
+	}
+	if got := ds.NumRecords(); got != -1 {
+		t.Errorf("NumRecords - got: %d, want: -1", got)
+	}
+
+	open := func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader([]byte(peopleCSV))), nil
+	}
+	ds = NewFromReader(
+		"people",
+		open,
+		true,
+		',',
+		peopleSchema,
+		Options{IndexFilename: "people.idx"},
+	)
+	if got := ds.NumRecords(); got != -1 {
+		t.Errorf("NumRecords - got: %d, want: -1", got)
+	}
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	wantErr := errors.New("can't seek as file isn't seekable: people")
+	err = conn.(*DCSVConn).SeekRecord(0)
+	if !testhelpers.ErrorMatch(err, wantErr) {
+		t.Errorf("SeekRecord - got: %v, want: %s", err, wantErr)
+	}
+}
+
+func TestNewFromReader_shortStream(t *testing.T) {
+	// The stream has less data available than is needed to rule out it
+	// being compressed, but Open mustn't wait for more
+	for _, data := range []string{"a,b\n", "a\n"} {
+		if err := checkOpenStream(data); err != nil {
+			t.Errorf("checkOpenStream(%q): %s", data, err)
+		}
+	}
+}
+
+// checkOpenStream checks that a Dataset reading a stream which has data
+// available but is never closed can be opened
+func checkOpenStream(data string) error {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go pw.Write([]byte(data))
+	open := func() (io.ReadCloser, error) {
+		return pr, nil
+	}
+	ds := NewFromReader(
+		"stream",
+		open,
+		true,
+		',',
+		ddataset.NewSchema([]string{"a", "b"}),
+		Options{},
+	)
+	opened := make(chan error, 1)
+	go func() {
+		_, err := ds.Open()
+		opened <- err
+	}()
+	select {
+	case err := <-opened:
+		return err
+	case <-time.After(5 * time.Second):
+		return errors.New("Open - didn't return")
+	}
+}
+
+// checkConnsIndependent checks that two connections to ds opened at the
+// same time each read all of wantRecords
+func checkConnsIndependent(
+	ds ddataset.Dataset,
+	wantRecords []ddataset.Record,
+) error {
+	conn1, err := ds.Open()
+	if err != nil {
+		return err
+	}
+	defer conn1.Close()
+	conn2, err := ds.Open()
+	if err != nil {
+		return err
+	}
+	defer conn2.Close()
+	for _, wantRecord := range wantRecords {
+		for _, conn := range []ddataset.Conn{conn1, conn2} {
+			if !conn.Next() {
+				return errors.New("Next - return false early")
+			}
+			if got := conn.Read(); !testhelpers.MatchRecords(got, wantRecord) {
+				return fmt.Errorf("Read - got: %s, want: %s", got, wantRecord)
+			}
+		}
+	}
+	for _, conn := range []ddataset.Conn{conn1, conn2} {
+		if conn.Next() {
+			return errors.New("Next - got: true, want: false")
+		}
+		if err := conn.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
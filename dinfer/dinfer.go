@@ -0,0 +1,186 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+// Package dinfer infers the types of the fields in a Dataset
+package dinfer
+
+import (
+	"strconv"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal"
+	"github.com/lawrencewoodman/dlit"
+)
+
+// MaxExamples is the maximum number of distinct example values
+// recorded for each field
+const MaxExamples = 5
+
+// Report describes the fields of a Dataset as inferred from its records
+type Report struct {
+	// NumRecords is the number of records scanned
+	NumRecords int64
+	// Fields describes each field in the same order as Dataset.Fields
+	Fields []Field
+}
+
+// Field describes what has been inferred about a single field
+type Field struct {
+	// Name is the name of the field
+	Name string
+	// Kind is the narrowest kind which is consistent with every non-empty
+	// value of the field.  Dates are reported as ddataset.KindTime.  If
+	// every value is empty then the Kind is ddataset.KindUnknown.
+	Kind ddataset.Kind
+	// NumEmpty is the number of records where the field is null or empty
+	NumEmpty int64
+	// EmptyRatio is the proportion of records where the field is null
+	// or empty
+	EmptyRatio float64
+	// Examples are up to MaxExamples distinct non-empty values of the field
+	// in the order they were found
+	Examples []string
+}
+
+// fieldInfo tracks which kinds are still consistent with a field
+type fieldInfo struct {
+	name      string
+	canBool   bool
+	canInt    bool
+	canFloat  bool
+	canTime   bool
+	numValues int64
+	numEmpty  int64
+	examples  []string
+}
+
+// Infer scans the first maxRecords records of ds and reports what kind
+// of values each field holds.  If maxRecords is less than 1 then all
+// the records are scanned.
+func Infer(ds ddataset.Dataset, maxRecords int64) (*Report, error) {
+	conn, err := ds.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	fieldNames := ds.Fields()
+	infos := make([]*fieldInfo, len(fieldNames))
+	for i, name := range fieldNames {
+		infos[i] = newFieldInfo(name)
+	}
+
+	numRecords := int64(0)
+	for (maxRecords < 1 || numRecords < maxRecords) && conn.Next() {
+		numRecords++
+		record := conn.Read()
+		for _, fi := range infos {
+			fi.add(record[fi.name])
+		}
+	}
+	if err := conn.Err(); err != nil {
+		return nil, err
+	}
+
+	fields := make([]Field, len(infos))
+	for i, fi := range infos {
+		fields[i] = fi.field(numRecords)
+	}
+	return &Report{NumRecords: numRecords, Fields: fields}, nil
+}
+
+// Schema returns a Schema based on the Report.  A field is marked as
+// Nullable if any of its values were null or empty.
+func (r *Report) Schema() ddataset.Schema {
+	schema := make(ddataset.Schema, len(r.Fields))
+	for i, f := range r.Fields {
+		schema[i] = ddataset.Field{
+			Name:     f.Name,
+			Kind:     f.Kind,
+			Nullable: f.NumEmpty > 0,
+		}
+	}
+	return schema
+}
+
+func newFieldInfo(name string) *fieldInfo {
+	return &fieldInfo{
+		name:     name,
+		canBool:  true,
+		canInt:   true,
+		canFloat: true,
+		canTime:  true,
+		examples: make([]string, 0, MaxExamples),
+	}
+}
+
+func (fi *fieldInfo) add(l *dlit.Literal) {
+	if ddataset.IsNull(l) || l.String() == "" {
+		fi.numEmpty++
+		return
+	}
+	s := l.String()
+	fi.numValues++
+	fi.addExample(s)
+
+	_, isInt := l.Int()
+	_, isFloat := l.Float()
+	fi.canInt = fi.canInt && isInt
+	fi.canFloat = fi.canFloat && isFloat
+	fi.canBool = fi.canBool && !isInt && isBool(s)
+	fi.canTime = fi.canTime && isTime(s)
+}
+
+func (fi *fieldInfo) addExample(v string) {
+	if len(fi.examples) >= MaxExamples {
+		return
+	}
+	for _, e := range fi.examples {
+		if e == v {
+			return
+		}
+	}
+	fi.examples = append(fi.examples, v)
+}
+
+func (fi *fieldInfo) field(numRecords int64) Field {
+	emptyRatio := 0.0
+	if numRecords > 0 {
+		emptyRatio = float64(fi.numEmpty) / float64(numRecords)
+	}
+	return Field{
+		Name:       fi.name,
+		Kind:       fi.kind(),
+		NumEmpty:   fi.numEmpty,
+		EmptyRatio: emptyRatio,
+		Examples:   fi.examples,
+	}
+}
+
+func (fi *fieldInfo) kind() ddataset.Kind {
+	switch {
+	case fi.numValues == 0:
+		return ddataset.KindUnknown
+	case fi.canBool:
+		return ddataset.KindBool
+	case fi.canInt:
+		return ddataset.KindInt
+	case fi.canFloat:
+		return ddataset.KindFloat
+	case fi.canTime:
+		return ddataset.KindTime
+	}
+	return ddataset.KindString
+}
+
+// isBool returns whether s is a boolean.  Integers such as 0 and 1
+// aren't treated as booleans.
+func isBool(s string) bool {
+	_, err := strconv.ParseBool(s)
+	return err == nil
+}
+
+func isTime(s string) bool {
+	_, ok := internal.ParseTime(s)
+	return ok
+}
@@ -0,0 +1,180 @@
+package dinfer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/dcsv"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+)
+
+func TestInfer(t *testing.T) {
+	cases := []struct {
+		filename   string
+		separator  rune
+		fieldNames []string
+		maxRecords int64
+		want       *Report
+	}{
+		{filename: filepath.Join("fixtures", "mixed.csv"),
+			separator: ',',
+			fieldNames: []string{
+				"id", "score", "member", "joined", "notes", "height",
+			},
+			maxRecords: 0,
+			want: &Report{
+				NumRecords: 5,
+				Fields: []Field{
+					{Name: "id", Kind: ddataset.KindInt,
+						Examples: []string{"1", "2", "3", "4", "5"}},
+					{Name: "score", Kind: ddataset.KindFloat,
+						NumEmpty: 1, EmptyRatio: 0.2,
+						Examples: []string{"12.5", "7", "3.25", "8"}},
+					{Name: "member", Kind: ddataset.KindBool,
+						Examples: []string{"true", "false", "TRUE", "True"}},
+					{Name: "joined", Kind: ddataset.KindTime,
+						NumEmpty: 1, EmptyRatio: 0.2,
+						Examples: []string{
+							"2016-01-02", "2016-03-04 10:00:00", "2017-11-30",
+							"2018-07-09T08:30:00Z",
+						}},
+					{Name: "notes", Kind: ddataset.KindString,
+						NumEmpty: 3, EmptyRatio: 0.6,
+						Examples: []string{"late payer", "said \"hello\""}},
+					{Name: "height", Kind: ddataset.KindFloat,
+						NumEmpty: 1, EmptyRatio: 0.2,
+						Examples: []string{"1.80", "1.65", "1.7", "2"}},
+				},
+			},
+		},
+		{filename: filepath.Join("fixtures", "mixed.csv"),
+			separator: ',',
+			fieldNames: []string{
+				"id", "score", "member", "joined", "notes", "height",
+			},
+			maxRecords: 2,
+			want: &Report{
+				NumRecords: 2,
+				Fields: []Field{
+					{Name: "id", Kind: ddataset.KindInt,
+						Examples: []string{"1", "2"}},
+					{Name: "score", Kind: ddataset.KindFloat,
+						Examples: []string{"12.5", "7"}},
+					{Name: "member", Kind: ddataset.KindBool,
+						Examples: []string{"true", "false"}},
+					{Name: "joined", Kind: ddataset.KindTime,
+						Examples: []string{"2016-01-02", "2016-03-04 10:00:00"}},
+					{Name: "notes", Kind: ddataset.KindString,
+						NumEmpty: 1, EmptyRatio: 0.5,
+						Examples: []string{"late payer"}},
+					{Name: "height", Kind: ddataset.KindFloat,
+						NumEmpty: 1, EmptyRatio: 0.5,
+						Examples: []string{"1.80"}},
+				},
+			},
+		},
+		{filename: filepath.Join("fixtures", "debt.csv"),
+			separator: ',',
+			fieldNames: []string{
+				"name", "balance", "numCards", "martialStatus",
+				"tertiaryEducated", "success",
+			},
+			maxRecords: 3,
+			want: &Report{
+				NumRecords: 3,
+				Fields: []Field{
+					{Name: "name", Kind: ddataset.KindString,
+						Examples: []string{
+							"Paul Robertson", "William George", "William Pixi",
+						}},
+					{Name: "balance", Kind: ddataset.KindInt,
+						Examples: []string{"-291417", "-281603", "-243865"}},
+					{Name: "numCards", Kind: ddataset.KindInt,
+						Examples: []string{"19", "7", "0"}},
+					{Name: "martialStatus", Kind: ddataset.KindString,
+						Examples: []string{"divorced", "single", "married"}},
+					{Name: "tertiaryEducated", Kind: ddataset.KindBool,
+						Examples: []string{"false", "true"}},
+					{Name: "success", Kind: ddataset.KindBool,
+						Examples: []string{"false", "true"}},
+				},
+			},
+		},
+	}
+	for i, c := range cases {
+		ds := dcsv.New(c.filename, true, c.separator, c.fieldNames)
+		got, err := Infer(ds, c.maxRecords)
+		if err != nil {
+			t.Fatalf("(%d) Infer: %s", i, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("(%d) Infer - got: %v, want: %v", i, got, c.want)
+		}
+	}
+}
+
+func TestInfer_bank(t *testing.T) {
+	filename := filepath.Join("fixtures", "bank.csv")
+	fieldNames := []string{
+		"age", "job", "marital", "education", "default", "balance",
+		"housing", "loan", "contact", "day", "month", "duration", "campaign",
+		"pdays", "previous", "poutcome", "y",
+	}
+	wantKinds := map[string]ddataset.Kind{
+		"age":       ddataset.KindInt,
+		"job":       ddataset.KindString,
+		"default":   ddataset.KindString,
+		"balance":   ddataset.KindInt,
+		"month":     ddataset.KindString,
+		"pdays":     ddataset.KindInt,
+		"poutcome":  ddataset.KindString,
+		"education": ddataset.KindString,
+	}
+	ds := dcsv.New(filename, true, ';', fieldNames)
+	report, err := Infer(ds, -1)
+	if err != nil {
+		t.Fatalf("Infer: %s", err)
+	}
+	if report.NumRecords != 9 {
+		t.Errorf("NumRecords - got: %d, want: 9", report.NumRecords)
+	}
+	schema := report.Schema()
+	if got := schema.Names(); !reflect.DeepEqual(got, fieldNames) {
+		t.Errorf("Schema().Names() - got: %s, want: %s", got, fieldNames)
+	}
+	for name, wantKind := range wantKinds {
+		f, ok := schema.Field(name)
+		if !ok {
+			t.Errorf("Schema().Field(%s) - not found", name)
+			continue
+		}
+		if f.Kind != wantKind || f.Nullable {
+			t.Errorf("Schema().Field(%s) - got: %v, want Kind: %s",
+				name, f, wantKind)
+		}
+	}
+}
+
+func TestInfer_errors(t *testing.T) {
+	filename := filepath.Join("fixtures", "bank.csv")
+	fieldNames := []string{"age", "job"}
+	ds := dcsv.New(filename, true, ';', fieldNames)
+	if _, err := Infer(ds, 0); !errors.Is(err, ddataset.ErrWrongNumFields) {
+		t.Errorf("Infer - got err: %v, want: %s",
+			err, ddataset.ErrWrongNumFields)
+	}
+
+	filename = filepath.Join("fixtures", "missing.csv")
+	wantErr := &os.PathError{Op: "open", Path: filename, Err: syscall.ENOENT}
+	ds = dcsv.New(filename, true, ';', fieldNames)
+	_, err := Infer(ds, 0)
+	if err := testhelpers.CheckPathErrorMatch(err, wantErr); err != nil {
+		t.Errorf("Infer - filename: %s - problem with error: %s",
+			filename, err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dsql
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/dlit"
+)
+
+// column holds the value scanned from a database column.  Only the
+// field matching kind is used unless the value couldn't be scanned as
+// kind, in which case it is held as a string.
+type column struct {
+	kind ddataset.Kind
+	s    sql.NullString
+	i    sql.NullInt64
+	f    sql.NullFloat64
+	b    sql.NullBool
+	// mismatch is whether the value didn't match kind
+	mismatch bool
+}
+
+var (
+	nullInt64Type   = reflect.TypeOf(sql.NullInt64{})
+	nullInt32Type   = reflect.TypeOf(sql.NullInt32{})
+	nullInt16Type   = reflect.TypeOf(sql.NullInt16{})
+	nullByteType    = reflect.TypeOf(sql.NullByte{})
+	nullFloat64Type = reflect.TypeOf(sql.NullFloat64{})
+	nullBoolType    = reflect.TypeOf(sql.NullBool{})
+)
+
+// makeColumns creates the columns to scan rows into.  If allStrings is
+// true or the column types are unknown then every column is scanned
+// as a string.
+func makeColumns(rows *sql.Rows, allStrings bool) ([]column, error) {
+	if allStrings {
+		names, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		columns := make([]column, len(names))
+		for i := range columns {
+			columns[i].kind = ddataset.KindString
+		}
+		return columns, nil
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]column, len(columnTypes))
+	for i, ct := range columnTypes {
+		if isDecimalType(ct.DatabaseTypeName()) {
+			// Scanned as a string so that exact decimals aren't lost
+			columns[i].kind = ddataset.KindString
+			continue
+		}
+		columns[i].kind = scanKind(ct.ScanType())
+	}
+	return columns, nil
+}
+
+// isDecimalType returns whether the database type name is for exact
+// decimal numbers
+func isDecimalType(typeName string) bool {
+	typeName = strings.ToUpper(typeName)
+	return strings.HasPrefix(typeName, "DECIMAL") ||
+		strings.HasPrefix(typeName, "NUMERIC")
+}
+
+// scanKind returns the Kind to scan a column with scan type t into
+func scanKind(t reflect.Type) ddataset.Kind {
+	if t == nil {
+		return ddataset.KindString
+	}
+	switch t {
+	case nullInt64Type, nullInt32Type, nullInt16Type, nullByteType:
+		return ddataset.KindInt
+	case nullFloat64Type:
+		return ddataset.KindFloat
+	case nullBoolType:
+		return ddataset.KindBool
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return ddataset.KindInt
+	case reflect.Float32, reflect.Float64:
+		return ddataset.KindFloat
+	case reflect.Bool:
+		return ddataset.KindBool
+	}
+	return ddataset.KindString
+}
+
+// ptr returns a pointer suitable for passing to sql.Rows.Scan
+func (c *column) ptr() interface{} {
+	return c
+}
+
+// Scan implements sql.Scanner.  If src can't be converted to kind, such
+// as text in an SQLite INTEGER column, it is held as a string rather
+// than returning an error.
+func (c *column) Scan(src interface{}) error {
+	c.mismatch = false
+	var err error
+	switch c.kind {
+	case ddataset.KindInt:
+		err = c.i.Scan(src)
+	case ddataset.KindFloat:
+		err = c.f.Scan(src)
+	case ddataset.KindBool:
+		err = c.b.Scan(src)
+	default:
+		return c.s.Scan(src)
+	}
+	if err != nil {
+		c.mismatch = true
+		return c.s.Scan(src)
+	}
+	return nil
+}
+
+// literal returns the scanned value as a Literal
+func (c *column) literal() *dlit.Literal {
+	if c.mismatch {
+		return dlit.NewString(c.s.String)
+	}
+	switch c.kind {
+	case ddataset.KindInt:
+		if c.i.Valid {
+			return dlit.MustNew(c.i.Int64)
+		}
+	case ddataset.KindFloat:
+		if c.f.Valid {
+			return dlit.MustNew(c.f.Float64)
+		}
+	case ddataset.KindBool:
+		if c.b.Valid {
+			return dlit.MustNew(c.b.Bool)
+		}
+	default:
+		if c.s.Valid {
+			return dlit.NewString(c.s.String)
+		}
+	}
+	return ddataset.NewNull()
+}
+
+// value returns the scanned value as the Go type it was scanned into,
+// a string if it didn't match kind or nil if it is null
+func (c *column) value() interface{} {
+	if c.mismatch {
+		return c.s.String
+	}
+	switch c.kind {
+	case ddataset.KindInt:
+		if c.i.Valid {
+			return c.i.Int64
+		}
+	case ddataset.KindFloat:
+		if c.f.Valid {
+			return c.f.Float64
+		}
+	case ddataset.KindBool:
+		if c.b.Valid {
+			return c.b.Bool
+		}
+	default:
+		if c.s.Valid {
+			return c.s.String
+		}
+	}
+	return nil
+}
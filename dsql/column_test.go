@@ -0,0 +1,76 @@
+package dsql
+
+import (
+	"testing"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
+)
+
+func TestColumnScan(t *testing.T) {
+	cases := []struct {
+		kind      ddataset.Kind
+		src       interface{}
+		want      *dlit.Literal
+		wantValue interface{}
+	}{
+		{kind: ddataset.KindInt, src: int64(7),
+			want: dlit.MustNew(7), wantValue: int64(7)},
+		{kind: ddataset.KindInt, src: []byte("12"),
+			want: dlit.MustNew(12), wantValue: int64(12)},
+		{kind: ddataset.KindInt, src: []byte("n/a"),
+			want: dlit.NewString("n/a"), wantValue: "n/a"},
+		{kind: ddataset.KindInt, src: 1.5,
+			want: dlit.NewString("1.5"), wantValue: "1.5"},
+		{kind: ddataset.KindInt, src: nil,
+			want: ddataset.NewNull(), wantValue: nil},
+		{kind: ddataset.KindFloat, src: "unknown",
+			want: dlit.NewString("unknown"), wantValue: "unknown"},
+		{kind: ddataset.KindBool, src: "maybe",
+			want: dlit.NewString("maybe"), wantValue: "maybe"},
+		{kind: ddataset.KindBool, src: int64(1),
+			want: dlit.MustNew(true), wantValue: true},
+		{kind: ddataset.KindString, src: []byte("0.10"),
+			want: dlit.NewString("0.10"), wantValue: "0.10"},
+	}
+	for i, c := range cases {
+		col := &column{kind: c.kind}
+		// Scan a valid value first to check mismatch is reset
+		if err := col.Scan(int64(1)); err != nil {
+			t.Fatalf("(%d) Scan: %s", i, err)
+		}
+		if err := col.Scan(c.src); err != nil {
+			t.Fatalf("(%d) Scan: %s", i, err)
+		}
+		got := ddataset.Record{"v": col.literal()}
+		want := ddataset.Record{"v": c.want}
+		if !testhelpers.MatchRecords(got, want) {
+			t.Errorf("(%d) literal - got: %s, want: %s", i, got, want)
+		}
+		if got := col.value(); got != c.wantValue {
+			t.Errorf("(%d) value - got: %v (%T), want: %v (%T)",
+				i, got, got, c.wantValue, c.wantValue)
+		}
+	}
+}
+
+func TestIsDecimalType(t *testing.T) {
+	cases := []struct {
+		typeName string
+		want     bool
+	}{
+		{typeName: "DECIMAL", want: true},
+		{typeName: "DECIMAL(10,2)", want: true},
+		{typeName: "numeric", want: true},
+		{typeName: "INTEGER", want: false},
+		{typeName: "REAL", want: false},
+		{typeName: "", want: false},
+	}
+	for i, c := range cases {
+		if got := isDecimalType(c.typeName); got != c.want {
+			t.Errorf("(%d) isDecimalType(%s) - got: %t, want: %t",
+				i, c.typeName, got, c.want)
+		}
+	}
+}
@@ -10,30 +10,42 @@
 package dsql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/lawrencewoodman/ddataset"
 	"github.com/lawrencewoodman/ddataset/internal"
-	"github.com/lawrencewoodman/dlit"
 )
 
 // DSQL represents a SQL database Dataset
 type DSQL struct {
 	dbHandler  DBHandler
-	openConn   int
+	conns      map[*DSQLConn]bool
 	fieldNames []string
+	schema     ddataset.Schema
+	options    Options
 	isReleased bool
+	mu         sync.Mutex
 }
 
 // DSQLConn represents a connection to a DSQL Dataset
 type DSQLConn struct {
+	ctx           context.Context
 	dataset       *DSQL
 	rows          *sql.Rows
-	row           []sql.NullString
+	columns       []column
+	fieldColumns  []int
 	rowPtrs       []interface{}
 	currentRecord ddataset.Record
+	keyColumn     int
+	numInPage     int
+	lastKey       interface{}
 	err           error
+	isClosed      bool
+	mu            sync.Mutex
 }
 
 // DBHandler handles basic access to an Sql database
@@ -43,58 +55,236 @@ type DBHandler interface {
 	// Rows returns the rows for the database with each row having
 	// the same number and same order of fields as those passed
 	// to New.  However, the fields don't have to have the same names.
+	// If Options.MatchByName is set then the columns are instead
+	// matched to the fields by name.
 	Rows() (*sql.Rows, error)
 	// Close closes the database
 	Close() error
 }
 
+// ContextDBHandler is implemented by DBHandlers which can bind the
+// rows they return to a context.Context
+type ContextDBHandler interface {
+	// RowsContext is like Rows but the query is run using ctx, such as
+	// via sql.DB.QueryContext
+	RowsContext(ctx context.Context) (*sql.Rows, error)
+}
+
+// OpenContextDBHandler is implemented by DBHandlers which can bind
+// opening the database to a context.Context
+type OpenContextDBHandler interface {
+	// OpenContext is like Open but ctx can cancel or time out opening
+	// the database
+	OpenContext(ctx context.Context) error
+}
+
+// CountDBHandler is implemented by DBHandlers which can count the rows
+// that Rows would return without reading them, such as by using
+// SELECT COUNT(*)
+type CountDBHandler interface {
+	// Count returns the number of rows that Rows would return.  It is
+	// only called while the DBHandler is open.
+	Count() (int64, error)
+}
+
+// PageDBHandler is implemented by DBHandlers which can return their rows
+// in pages ordered by a key column.  This lets a Dataset be read without
+// keeping a single cursor open for the whole of a scan.
+type PageDBHandler interface {
+	// KeyColumn returns the name of the column that pages are ordered by.
+	// Its values must be unique and not null.
+	KeyColumn() string
+	// PageRows returns up to pageSize rows, ordered by the key column,
+	// whose key is greater than after.  If after is nil then the rows
+	// start from the first row.  It is only called while the DBHandler
+	// is open.
+	PageRows(
+		ctx context.Context,
+		after interface{},
+		pageSize int,
+	) (*sql.Rows, error)
+}
+
+// PushdownDBHandler is implemented by DBHandlers which can apply a
+// ddataset.Pushdown natively by rewriting their query
+type PushdownDBHandler interface {
+	// PushdownRows is like RowsContext but the query has as much of p
+	// applied as the DBHandler can manage.  It returns a copy of p with
+	// the parts that were applied cleared.
+	PushdownRows(
+		ctx context.Context,
+		p ddataset.Pushdown,
+	) (*sql.Rows, ddataset.Pushdown, error)
+}
+
+// Options configures a DSQL Dataset created with NewWithOptions
+type Options struct {
+	// AllStrings makes every column be read as a string literal rather
+	// than a literal of the type reported for the column by the database
+	AllStrings bool
+	// MatchByName makes columns be matched to fields by name rather
+	// than by position.  An error is returned by Open if a field
+	// doesn't have a matching column.
+	MatchByName bool
+	// ColumnNames maps field names to the names of the columns they are
+	// read from when MatchByName is set.  Fields not in the map are read
+	// from the column with the same name as the field.
+	ColumnNames map[string]string
+	// IgnoreExtraColumns makes any columns which don't match a field be
+	// ignored when MatchByName is set.  Otherwise they cause Open to
+	// return an error.
+	IgnoreExtraColumns bool
+	// PageSize makes the rows be read in pages of PageSize rows using
+	// PageDBHandler, re-querying for each page with the key of the last
+	// row read.  If it is less than 1 then the rows are read using a single
+	// query.  If it is set and the DBHandler doesn't implement
+	// PageDBHandler then Open returns an error.
+	PageSize int
+}
+
 // New creates a new DSQL Dataset
 func New(dbHandler DBHandler, fieldNames []string) ddataset.Dataset {
+	return newDSQL(
+		dbHandler,
+		fieldNames,
+		ddataset.NewSchema(fieldNames),
+		Options{},
+	)
+}
+
+// NewWithSchema creates a new DSQL Dataset whose fields are described
+// by schema
+func NewWithSchema(
+	dbHandler DBHandler,
+	schema ddataset.Schema,
+) ddataset.Dataset {
+	return newDSQL(dbHandler, schema.Names(), schema, Options{})
+}
+
+// NewWithOptions creates a new DSQL Dataset whose fields are described
+// by schema and which is configured by options
+func NewWithOptions(
+	dbHandler DBHandler,
+	schema ddataset.Schema,
+	options Options,
+) ddataset.Dataset {
+	return newDSQL(dbHandler, schema.Names(), schema, options)
+}
+
+func newDSQL(
+	dbHandler DBHandler,
+	fieldNames []string,
+	schema ddataset.Schema,
+	options Options,
+) *DSQL {
 	return &DSQL{
 		dbHandler:  dbHandler,
-		openConn:   0,
+		conns:      map[*DSQLConn]bool{},
 		fieldNames: fieldNames,
+		schema:     schema,
+		options:    options,
 		isReleased: false,
 	}
 }
 
 // Open creates a connection to the Dataset
 func (d *DSQL) Open() (ddataset.Conn, error) {
-	if d.isReleased {
-		return nil, ddataset.ErrReleased
+	return d.OpenContext(context.Background())
+}
+
+// OpenContext creates a connection to the Dataset which stops returning
+// Records once ctx is done.  If the DBHandler implements ContextDBHandler
+// then ctx is also passed to its RowsContext method.
+func (d *DSQL) OpenContext(ctx context.Context) (ddataset.Conn, error) {
+	conn, _, err := d.openConn(ctx, nil, ddataset.Pushdown{})
+	return conn, err
+}
+
+// OpenPushdown is like OpenContext but if the DBHandler implements
+// PushdownDBHandler then as much of p is applied to its query as it can
+// manage.  It returns the parts of p which weren't applied.  Nothing is
+// pushed down if Options.PageSize is set.
+func (d *DSQL) OpenPushdown(
+	ctx context.Context,
+	p ddataset.Pushdown,
+) (ddataset.Conn, ddataset.Pushdown, error) {
+	return d.openConn(ctx, nil, p)
+}
+
+// OpenAfterKey is like OpenContext but the connection only returns the
+// Records whose key is greater than key.  This allows a scan to be
+// restarted from the value returned by DSQLConn.LastKey.  It can only be
+// used if Options.PageSize is set.
+func (d *DSQL) OpenAfterKey(
+	ctx context.Context,
+	key interface{},
+) (ddataset.Conn, error) {
+	if d.options.PageSize < 1 {
+		return nil, errors.New("OpenAfterKey requires Options.PageSize to be set")
 	}
-	if err := d.dbHandler.Open(); err != nil {
-		return nil, err
+	conn, _, err := d.openConn(ctx, key, ddataset.Pushdown{})
+	return conn, err
+}
+
+func (d *DSQL) openConn(
+	ctx context.Context,
+	after interface{},
+	p ddataset.Pushdown,
+) (ddataset.Conn, ddataset.Pushdown, error) {
+	if d.released() {
+		return nil, p, ddataset.ErrReleased
 	}
-	rows, err := d.dbHandler.Rows()
+	if err := d.openHandler(ctx); err != nil {
+		return nil, p, err
+	}
+	rows, remainder, err := d.rows(ctx, after, p)
 	if err != nil {
 		d.dbHandler.Close()
-		return nil, err
+		return nil, p, err
 	}
-	columns, err := rows.Columns()
+	columns, err := makeColumns(rows, d.options.AllStrings)
 	if err != nil {
+		rows.Close()
 		d.dbHandler.Close()
-		return nil, err
+		return nil, p, err
 	}
-	numColumns := len(columns)
-	if err := checkTableValid(d.fieldNames, numColumns); err != nil {
+	fieldColumns, err := d.mapFieldsToColumns(rows)
+	if err != nil {
+		rows.Close()
 		d.dbHandler.Close()
-		return nil, err
+		return nil, p, err
+	}
+	keyColumn, err := d.findKeyColumn(rows)
+	if err != nil {
+		rows.Close()
+		d.dbHandler.Close()
+		return nil, p, err
 	}
-	row := make([]sql.NullString, numColumns)
-	rowPtrs := make([]interface{}, numColumns)
-	for i := range d.fieldNames {
-		rowPtrs[i] = &row[i]
+	rowPtrs := make([]interface{}, len(columns))
+	for i := range columns {
+		rowPtrs[i] = columns[i].ptr()
 	}
 
-	return &DSQLConn{
+	conn := &DSQLConn{
+		ctx:           ctx,
 		dataset:       d,
 		rows:          rows,
-		row:           row,
+		columns:       columns,
+		fieldColumns:  fieldColumns,
 		rowPtrs:       rowPtrs,
-		currentRecord: make(ddataset.Record, numColumns),
+		currentRecord: make(ddataset.Record, len(d.fieldNames)),
+		keyColumn:     keyColumn,
+		numInPage:     0,
+		lastKey:       after,
 		err:           nil,
-	}, nil
+		isClosed:      false,
+	}
+	if err := d.addConn(conn); err != nil {
+		rows.Close()
+		d.dbHandler.Close()
+		return nil, p, err
+	}
+	return conn, remainder, nil
 }
 
 // Fields returns the field names used by the Dataset
@@ -102,47 +292,117 @@ func (d *DSQL) Fields() []string {
 	return d.fieldNames
 }
 
+// Schema returns the Schema of the Dataset
+func (d *DSQL) Schema() ddataset.Schema {
+	return d.schema
+}
+
 // NumRecords returns the number of records in the Dataset.  If there is
-// a problem getting the number of records it returns -1. NOTE: The returned
-// value can change if the underlying Dataset changes.
+// a problem getting the number of records it returns -1.  If the DBHandler
+// implements CountDBHandler then its Count method is used, otherwise each
+// record is read and counted. NOTE: The returned value can change if the
+// underlying Dataset changes.
 func (d *DSQL) NumRecords() int64 {
-	return internal.CountNumRecords(d)
+	h, ok := d.dbHandler.(CountDBHandler)
+	if !ok || d.released() {
+		return internal.CountNumRecords(d)
+	}
+	if err := d.dbHandler.Open(); err != nil {
+		return -1
+	}
+	defer d.dbHandler.Close()
+	numRecords, err := h.Count()
+	if err != nil {
+		return -1
+	}
+	return numRecords
 }
 
 // Release releases any resources associated with the Dataset d,
-// rendering it unusable in the future.
+// rendering it unusable in the future.  Any connections which are still
+// open are closed, so that their Next returns false and their Err
+// returns ddataset.ErrReleased.
 func (d *DSQL) Release() error {
-	if !d.isReleased {
-		d.isReleased = true
-		return nil
+	d.mu.Lock()
+	if d.isReleased {
+		d.mu.Unlock()
+		return ddataset.ErrReleased
+	}
+	d.isReleased = true
+	conns := make([]*DSQLConn, 0, len(d.conns))
+	for conn := range d.conns {
+		conns = append(conns, conn)
+	}
+	d.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.invalidate()
 	}
-	return ddataset.ErrReleased
+	return nil
 }
 
 // Next returns whether there is a Record to be Read
 func (c *DSQLConn) Next() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.err != nil {
 		return false
 	}
-	if c.rows.Next() {
-		if err := c.rows.Scan(c.rowPtrs...); err != nil {
-			c.Close()
-			c.err = err
-			return false
-		}
-		c.makeRowCurrentRecord()
-		return true
+	if c.isClosed {
+		c.err = ddataset.ErrConnClosed
+		return false
 	}
-	if err := c.rows.Err(); err != nil {
-		c.Close()
+	if err := c.ctx.Err(); err != nil {
+		c.close()
 		c.err = err
 		return false
 	}
-	return false
+	for {
+		if c.rows.Next() {
+			if err := c.rows.Scan(c.rowPtrs...); err != nil {
+				c.close()
+				c.err = err
+				return false
+			}
+			if c.dataset.options.PageSize > 0 {
+				c.numInPage++
+				c.lastKey = c.columns[c.keyColumn].value()
+			}
+			c.makeRowCurrentRecord()
+			return true
+		}
+		if err := c.rows.Err(); err != nil {
+			c.close()
+			c.err = err
+			return false
+		}
+		if c.dataset.options.PageSize < 1 ||
+			c.numInPage < c.dataset.options.PageSize {
+			return false
+		}
+		if err := c.nextPage(); err != nil {
+			c.close()
+			c.err = err
+			return false
+		}
+	}
+}
+
+// LastKey returns the value of the key column for the last Record read
+// when Options.PageSize is set.  This can be passed to DSQL.OpenAfterKey
+// to restart a scan after that Record.  If no Record has been read it
+// returns the key the connection was opened after, which is nil for
+// connections created by Open.
+func (c *DSQLConn) LastKey() interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastKey
 }
 
 // Err returns any errors from the connection
 func (c *DSQLConn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.err
 }
 
@@ -151,15 +411,187 @@ func (c *DSQLConn) Read() ddataset.Record {
 	return c.currentRecord
 }
 
-// Close closes the connection
+// Close closes the connection.  It is safe to call Close more than once.
 func (c *DSQLConn) Close() error {
-	return c.dataset.dbHandler.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.close()
+}
+
+// close closes the rows of the connection and releases its use of the
+// DBHandler.  c.mu must be held by the caller.
+func (c *DSQLConn) close() error {
+	if c.isClosed {
+		return nil
+	}
+	c.isClosed = true
+	rowsErr := c.rows.Close()
+	handlerErr := c.dataset.dbHandler.Close()
+	c.dataset.removeConn(c)
+	if rowsErr != nil {
+		return rowsErr
+	}
+	return handlerErr
+}
+
+// invalidate closes the connection because its Dataset has been released
+func (c *DSQLConn) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.isClosed {
+		c.close()
+		if c.err == nil {
+			c.err = ddataset.ErrReleased
+		}
+	}
+}
+
+// nextPage replaces the rows of the connection with the next page of
+// rows.  c.mu must be held by the caller.
+func (c *DSQLConn) nextPage() error {
+	if err := c.rows.Close(); err != nil {
+		return err
+	}
+	h := c.dataset.dbHandler.(PageDBHandler)
+	rows, err := h.PageRows(c.ctx, c.lastKey, c.dataset.options.PageSize)
+	if err != nil {
+		return err
+	}
+	c.rows = rows
+	c.numInPage = 0
+	return nil
 }
 
 func (c *DSQLConn) makeRowCurrentRecord() {
-	for i, v := range c.row {
-		c.currentRecord[c.dataset.fieldNames[i]] = dlit.NewString(v.String)
+	for i, columnIndex := range c.fieldColumns {
+		c.currentRecord[c.dataset.fieldNames[i]] =
+			c.columns[columnIndex].literal()
+	}
+}
+
+func (d *DSQL) released() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.isReleased
+}
+
+// addConn records that conn is open so that it can be closed by Release
+func (d *DSQL) addConn(conn *DSQLConn) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.isReleased {
+		return ddataset.ErrReleased
+	}
+	d.conns[conn] = true
+	return nil
+}
+
+func (d *DSQL) removeConn(conn *DSQLConn) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.conns, conn)
+}
+
+// openHandler opens the DBHandler using ctx if it implements
+// OpenContextDBHandler
+func (d *DSQL) openHandler(ctx context.Context) error {
+	if h, ok := d.dbHandler.(OpenContextDBHandler); ok {
+		return h.OpenContext(ctx)
+	}
+	return d.dbHandler.Open()
+}
+
+// rows returns the rows for a new connection and the parts of p which
+// weren't applied to them
+func (d *DSQL) rows(
+	ctx context.Context,
+	after interface{},
+	p ddataset.Pushdown,
+) (*sql.Rows, ddataset.Pushdown, error) {
+	if d.options.PageSize > 0 {
+		h, ok := d.dbHandler.(PageDBHandler)
+		if !ok {
+			return nil, p, errors.New("DBHandler doesn't support pagination")
+		}
+		rows, err := h.PageRows(ctx, after, d.options.PageSize)
+		return rows, p, err
+	}
+	if h, ok := d.dbHandler.(PushdownDBHandler); ok {
+		return h.PushdownRows(ctx, p)
+	}
+	if h, ok := d.dbHandler.(ContextDBHandler); ok {
+		rows, err := h.RowsContext(ctx)
+		return rows, p, err
+	}
+	rows, err := d.dbHandler.Rows()
+	return rows, p, err
+}
+
+// findKeyColumn returns the index of the key column in rows when
+// Options.PageSize is set, otherwise it returns -1
+func (d *DSQL) findKeyColumn(rows *sql.Rows) (int, error) {
+	if d.options.PageSize < 1 {
+		return -1, nil
+	}
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return -1, err
+	}
+	keyColumn := d.dbHandler.(PageDBHandler).KeyColumn()
+	for i, name := range columnNames {
+		if name == keyColumn {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("key column doesn't exist: %s", keyColumn)
+}
+
+// mapFieldsToColumns returns the index of the column in rows that
+// each field is read from
+func (d *DSQL) mapFieldsToColumns(rows *sql.Rows) ([]int, error) {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	fieldColumns := make([]int, len(d.fieldNames))
+	if !d.options.MatchByName {
+		if err := checkTableValid(d.fieldNames, len(columnNames)); err != nil {
+			return nil, err
+		}
+		for i := range fieldColumns {
+			fieldColumns[i] = i
+		}
+		return fieldColumns, nil
+	}
+
+	columnIndex := make(map[string]int, len(columnNames))
+	for i, name := range columnNames {
+		columnIndex[name] = i
+	}
+	usedColumns := make(map[int]bool, len(columnNames))
+	for i, fieldName := range d.fieldNames {
+		columnName := fieldName
+		if name, ok := d.options.ColumnNames[fieldName]; ok {
+			columnName = name
+		}
+		j, ok := columnIndex[columnName]
+		if !ok {
+			return nil, fmt.Errorf(
+				"column doesn't exist for field: %s, column: %s",
+				fieldName, columnName,
+			)
+		}
+		fieldColumns[i] = j
+		usedColumns[j] = true
+	}
+	if !d.options.IgnoreExtraColumns {
+		for j, name := range columnNames {
+			if !usedColumns[j] {
+				return nil, fmt.Errorf("column doesn't match a field: %s", name)
+			}
+		}
 	}
+	return fieldColumns, nil
 }
 
 func checkTableValid(fieldNames []string, numColumns int) error {
@@ -1,32 +1,45 @@
-// TODO: Test NULL handling of null values in database
 // TODO: Test Next, Err for errors - using a mock database
 // TODO: Test Open most fully for errors - using a mock database
 // TODO: Generate an error from Next() by creating a database then closing it
 //       after one run through for next() loop then run next() again
 
-// +build -nosqlite3
+//go:build !nosqlite3
+// +build !nosqlite3
 
 package dsql
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
 
 	"github.com/lawrencewoodman/ddataset"
-	"github.com/lawrencewoodman/ddataset/internal"
+	"github.com/lawrencewoodman/ddataset/dsqlite"
+	"github.com/lawrencewoodman/ddataset/dtruncate"
 	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
 	"github.com/lawrencewoodman/dlit"
 )
 
+func newSqlite3Handler(filename, tableName string) DBHandler {
+	return dsqlite.NewTableHandler(
+		filename,
+		tableName,
+		dsqlite.Options{CacheMB: 64},
+	)
+}
+
 func TestNew(t *testing.T) {
 	filename := filepath.Join("fixtures", "users.db")
 	tableName := "userinfo"
 	fieldNames := []string{"uid", "username", "dept", "started"}
-	ds := New(internal.NewSqlite3Handler(filename, tableName, 64), fieldNames)
+	ds := New(newSqlite3Handler(filename, tableName), fieldNames)
 	if _, ok := ds.(*DSQL); !ok {
 		t.Errorf("New(...) want DSQL type, got type: %T", ds)
 	}
@@ -36,7 +49,7 @@ func TestOpen(t *testing.T) {
 	filename := filepath.Join("fixtures", "users.db")
 	tableName := "userinfo"
 	fieldNames := []string{"uid", "username", "dept", "started"}
-	ds := New(internal.NewSqlite3Handler(filename, tableName, 64), fieldNames)
+	ds := New(newSqlite3Handler(filename, tableName), fieldNames)
 	conn, err := ds.Open()
 	if err != nil {
 		t.Errorf("Open() err: %s", err)
@@ -72,7 +85,7 @@ func TestOpen_errors(t *testing.T) {
 	}
 	for _, c := range cases {
 		ds := New(
-			internal.NewSqlite3Handler(c.filename, c.tableName, 64),
+			newSqlite3Handler(c.filename, c.tableName),
 			c.fieldNames,
 		)
 		if _, err := ds.Open(); err.Error() != c.wantErr.Error() {
@@ -87,7 +100,7 @@ func TestOpen_error_released(t *testing.T) {
 	tableName := "userinfo"
 	fieldNames := []string{"uid", "username", "dept", "started"}
 	ds := New(
-		internal.NewSqlite3Handler(filename, tableName, 64),
+		newSqlite3Handler(filename, tableName),
 		fieldNames,
 	)
 	ds.Release()
@@ -101,7 +114,7 @@ func TestRelease_error(t *testing.T) {
 	tableName := "userinfo"
 	fieldNames := []string{"uid", "username", "dept", "started"}
 	ds := New(
-		internal.NewSqlite3Handler(filename, tableName, 64),
+		newSqlite3Handler(filename, tableName),
 		fieldNames,
 	)
 	if err := ds.Release(); err != nil {
@@ -118,7 +131,7 @@ func TestFields(t *testing.T) {
 	tableName := "userinfo"
 	fieldNames := []string{"uid", "username", "dept", "started"}
 	ds := New(
-		internal.NewSqlite3Handler(filename, tableName, 64),
+		newSqlite3Handler(filename, tableName),
 		fieldNames,
 	)
 	got := ds.Fields()
@@ -127,6 +140,29 @@ func TestFields(t *testing.T) {
 	}
 }
 
+func TestSchema(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.db")
+	tableName := "people"
+	schema := ddataset.Schema{
+		{Name: "name", Kind: ddataset.KindString},
+		{Name: "balance", Kind: ddataset.KindInt},
+		{Name: "numCards", Kind: ddataset.KindInt},
+		{Name: "martialStatus", Kind: ddataset.KindString},
+		{Name: "tertiaryEducated", Kind: ddataset.KindBool, Nullable: true},
+		{Name: "success", Kind: ddataset.KindBool},
+	}
+	ds := NewWithSchema(
+		newSqlite3Handler(filename, tableName),
+		schema,
+	)
+	if got := ddataset.SchemaOf(ds); !reflect.DeepEqual(got, schema) {
+		t.Errorf("Schema() - got: %v, want: %v", got, schema)
+	}
+	if got := ds.Fields(); !reflect.DeepEqual(got, schema.Names()) {
+		t.Errorf("Fields() - got: %s, want: %s", got, schema.Names())
+	}
+}
+
 func TestNumRecords(t *testing.T) {
 	cases := []struct {
 		filename   string
@@ -154,7 +190,7 @@ func TestNumRecords(t *testing.T) {
 	}
 	for i, c := range cases {
 		ds := New(
-			internal.NewSqlite3Handler(c.filename, c.tableName, 64),
+			newSqlite3Handler(c.filename, c.tableName),
 			c.fieldNames,
 		)
 		got := ds.NumRecords()
@@ -164,13 +200,36 @@ func TestNumRecords(t *testing.T) {
 	}
 }
 
+func TestNumRecords_noCount(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	tableName := "userinfo"
+	fieldNames := []string{"uid", "username", "dept", "started"}
+	h := rowsOnlyHandler{newSqlite3Handler(filename, tableName)}
+	if _, ok := DBHandler(h).(CountDBHandler); ok {
+		t.Fatalf("rowsOnlyHandler implements CountDBHandler")
+	}
+	ds := New(h, fieldNames)
+	if got := ds.NumRecords(); got != 5 {
+		t.Errorf("NumRecords - got: %d, want: 5", got)
+	}
+	ds.Release()
+	if got := ds.NumRecords(); got != -1 {
+		t.Errorf("NumRecords - got: %d, want: -1", got)
+	}
+}
+
+// rowsOnlyHandler hides any optional methods of a DBHandler
+type rowsOnlyHandler struct {
+	DBHandler
+}
+
 func TestNext(t *testing.T) {
 	wantNumRecords := 5
 	filename := filepath.Join("fixtures", "users.db")
 	tableName := "userinfo"
 	fieldNames := []string{"uid", "username", "dept", "started"}
 	ds := New(
-		internal.NewSqlite3Handler(filename, tableName, 64),
+		newSqlite3Handler(filename, tableName),
 		fieldNames,
 	)
 	conn, err := ds.Open()
@@ -226,13 +285,13 @@ func TestRead(t *testing.T) {
 		ddataset.Record{
 			"uid":       dlit.MustNew(5),
 			"name":      dlit.MustNew("George Eliot"),
-			"dpt":       dlit.MustNew(""),
+			"dpt":       ddataset.NewNull(),
 			"startDate": dlit.MustNew("2010-05-05 10:00:00"),
 		},
 	}
 
 	ds := New(
-		internal.NewSqlite3Handler(filename, tableName, 64),
+		newSqlite3Handler(filename, tableName),
 		fieldNames,
 	)
 	conn, err := ds.Open()
@@ -257,6 +316,565 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestRead_types(t *testing.T) {
+	filename := filepath.Join("fixtures", "types.db")
+	tableName := "measurements"
+	schema := ddataset.Schema{
+		{Name: "id", Kind: ddataset.KindInt},
+		{Name: "reading", Kind: ddataset.KindFloat, Nullable: true},
+		{Name: "label", Kind: ddataset.KindString, Nullable: true},
+		{Name: "ok", Kind: ddataset.KindBool, Nullable: true},
+	}
+	cases := []struct {
+		options     Options
+		wantRecords []ddataset.Record
+	}{
+		{options: Options{},
+			wantRecords: []ddataset.Record{
+				ddataset.Record{
+					"id":      dlit.MustNew(1),
+					"reading": dlit.MustNew(1),
+					"label":   dlit.MustNew("alpha"),
+					"ok":      dlit.MustNew(true),
+				},
+				ddataset.Record{
+					"id":      dlit.MustNew(2),
+					"reading": dlit.MustNew(0.1),
+					"label":   dlit.MustNew(""),
+					"ok":      dlit.MustNew(false),
+				},
+				ddataset.Record{
+					"id":      dlit.MustNew(3),
+					"reading": ddataset.NewNull(),
+					"label":   ddataset.NewNull(),
+					"ok":      ddataset.NewNull(),
+				},
+				ddataset.Record{
+					"id":      dlit.MustNew(4),
+					"reading": dlit.MustNew(-2.5),
+					"label":   dlit.MustNew("42"),
+					"ok":      dlit.MustNew(true),
+				},
+			},
+		},
+		{options: Options{AllStrings: true},
+			wantRecords: []ddataset.Record{
+				ddataset.Record{
+					"id":      dlit.NewString("1"),
+					"reading": dlit.NewString("1"),
+					"label":   dlit.NewString("alpha"),
+					"ok":      dlit.NewString("true"),
+				},
+				ddataset.Record{
+					"id":      dlit.NewString("2"),
+					"reading": dlit.NewString("0.1"),
+					"label":   dlit.NewString(""),
+					"ok":      dlit.NewString("false"),
+				},
+				ddataset.Record{
+					"id":      dlit.NewString("3"),
+					"reading": ddataset.NewNull(),
+					"label":   ddataset.NewNull(),
+					"ok":      ddataset.NewNull(),
+				},
+				ddataset.Record{
+					"id":      dlit.NewString("4"),
+					"reading": dlit.NewString("-2.5"),
+					"label":   dlit.NewString("42"),
+					"ok":      dlit.NewString("true"),
+				},
+			},
+		},
+	}
+	for i, c := range cases {
+		ds := NewWithOptions(
+			newSqlite3Handler(filename, tableName),
+			schema,
+			c.options,
+		)
+		conn, err := ds.Open()
+		if err != nil {
+			t.Fatalf("(%d) Open() err: %s", i, err)
+		}
+		for _, wantRecord := range c.wantRecords {
+			if !conn.Next() {
+				t.Fatalf("(%d) Next() - return false early", i)
+			}
+			record := conn.Read()
+			if !testhelpers.MatchRecords(record, wantRecord) {
+				t.Errorf("(%d) Read() got: %s, want: %s", i, record, wantRecord)
+			}
+		}
+		if conn.Next() {
+			t.Errorf("(%d) Next() - return true, despite having finished", i)
+		}
+		if err := conn.Err(); err != nil {
+			t.Errorf("(%d) Err() err: %s", i, err)
+		}
+		conn.Close()
+	}
+}
+
+func TestRead_mismatchedTypes(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dsql_types")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "types.db"))
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+	// SQLite lets any value be stored in a column whatever its type
+	_, err = db.Exec(`
+		CREATE TABLE readings (id INTEGER, reading REAL, price DECIMAL(10,2));
+		INSERT INTO readings VALUES (1, 1.5, 2.25);
+		INSERT INTO readings VALUES ('n/a', 'unknown', 'free');
+		INSERT INTO readings VALUES (3, NULL, NULL);
+	`)
+	if err != nil {
+		t.Fatalf("Exec: %s", err)
+	}
+	schema := ddataset.Schema{
+		{Name: "id", Kind: ddataset.KindInt},
+		{Name: "reading", Kind: ddataset.KindFloat, Nullable: true},
+		{Name: "price", Kind: ddataset.KindString, Nullable: true},
+	}
+	wantRecords := []ddataset.Record{
+		ddataset.Record{
+			"id":      dlit.MustNew(1),
+			"reading": dlit.MustNew(1.5),
+			"price":   dlit.NewString("2.25"),
+		},
+		ddataset.Record{
+			"id":      dlit.NewString("n/a"),
+			"reading": dlit.NewString("unknown"),
+			"price":   dlit.NewString("free"),
+		},
+		ddataset.Record{
+			"id":      dlit.MustNew(3),
+			"reading": ddataset.NewNull(),
+			"price":   ddataset.NewNull(),
+		},
+	}
+	ds := NewWithSchema(
+		NewHandlerFromDB(db, "SELECT * FROM readings", []interface{}{}),
+		schema,
+	)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open() err: %s", err)
+	}
+	defer conn.Close()
+	for _, wantRecord := range wantRecords {
+		if !conn.Next() {
+			t.Fatalf("Next() - return false early, err: %v", conn.Err())
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, wantRecord) {
+			t.Errorf("Read() got: %s, want: %s", record, wantRecord)
+		}
+	}
+	if conn.Next() {
+		t.Errorf("Next() - return true, despite having finished")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err() err: %s", err)
+	}
+}
+
+func TestRead_matchByName(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	tableName := "userinfo"
+	cases := []struct {
+		fieldNames []string
+		options    Options
+		wantRecord ddataset.Record
+	}{
+		{fieldNames: []string{"started", "dept", "username", "uid"},
+			options: Options{MatchByName: true},
+			wantRecord: ddataset.Record{
+				"started":  dlit.MustNew("2013-10-05 10:00:00"),
+				"dept":     dlit.MustNew("Logistics"),
+				"username": dlit.MustNew("Fred Wilkins"),
+				"uid":      dlit.MustNew(1),
+			},
+		},
+		{fieldNames: []string{"name", "id"},
+			options: Options{
+				MatchByName:        true,
+				ColumnNames:        map[string]string{"name": "username", "id": "uid"},
+				IgnoreExtraColumns: true,
+			},
+			wantRecord: ddataset.Record{
+				"name": dlit.MustNew("Fred Wilkins"),
+				"id":   dlit.MustNew(1),
+			},
+		},
+	}
+	for i, c := range cases {
+		ds := NewWithOptions(
+			newSqlite3Handler(filename, tableName),
+			ddataset.NewSchema(c.fieldNames),
+			c.options,
+		)
+		conn, err := ds.Open()
+		if err != nil {
+			t.Fatalf("(%d) Open() err: %s", i, err)
+		}
+		if !conn.Next() {
+			t.Fatalf("(%d) Next() - return false early", i)
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, c.wantRecord) {
+			t.Errorf("(%d) Read() got: %s, want: %s", i, record, c.wantRecord)
+		}
+		conn.Close()
+	}
+}
+
+func TestOpen_matchByName_errors(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	tableName := "userinfo"
+	cases := []struct {
+		fieldNames []string
+		options    Options
+		wantErr    error
+	}{
+		{fieldNames: []string{"uid", "username", "dept", "start"},
+			options: Options{MatchByName: true},
+			wantErr: errors.New(
+				"column doesn't exist for field: start, column: start",
+			),
+		},
+		{fieldNames: []string{"uid", "name"},
+			options: Options{
+				MatchByName: true,
+				ColumnNames: map[string]string{"name": "user"},
+			},
+			wantErr: errors.New(
+				"column doesn't exist for field: name, column: user",
+			),
+		},
+		{fieldNames: []string{"uid", "username", "dept"},
+			options: Options{MatchByName: true},
+			wantErr: errors.New("column doesn't match a field: started"),
+		},
+	}
+	for i, c := range cases {
+		ds := NewWithOptions(
+			newSqlite3Handler(filename, tableName),
+			ddataset.NewSchema(c.fieldNames),
+			c.options,
+		)
+		_, err := ds.Open()
+		if !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) Open() - got err: %v, want: %s", i, err, c.wantErr)
+		}
+	}
+}
+
+func TestRead_paged(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	tableName := "userinfo"
+	fieldNames := []string{"uid", "username"}
+	wantUIDs := []int64{1, 2, 3, 4, 5}
+	h := &countPagesHandler{
+		PageDBHandler: dsqlite.NewTableHandler(
+			filename,
+			tableName,
+			dsqlite.Options{KeyColumn: "uid"},
+		),
+	}
+	ds := NewWithOptions(
+		h,
+		ddataset.NewSchema(fieldNames),
+		Options{PageSize: 2, MatchByName: true, IgnoreExtraColumns: true},
+	)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	gotUIDs := []int64{}
+	for conn.Next() {
+		uid, ok := conn.Read()["uid"].Int()
+		if !ok {
+			t.Fatalf("Read - uid isn't an int: %s", conn.Read()["uid"])
+		}
+		gotUIDs = append(gotUIDs, uid)
+	}
+	if err := conn.Err(); err != nil {
+		t.Fatalf("Err: %s", err)
+	}
+	if !reflect.DeepEqual(gotUIDs, wantUIDs) {
+		t.Errorf("Read - got uids: %v, want: %v", gotUIDs, wantUIDs)
+	}
+	if h.numPages != 3 {
+		t.Errorf("PageRows - got calls: %d, want: 3", h.numPages)
+	}
+}
+
+func TestOpenAfterKey(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	tableName := "userinfo"
+	fieldNames := []string{"uid", "username"}
+	h := dsqlite.NewTableHandler(
+		filename,
+		tableName,
+		dsqlite.Options{KeyColumn: "uid"},
+	)
+	ds := NewWithOptions(
+		h,
+		ddataset.NewSchema(fieldNames),
+		Options{PageSize: 2, MatchByName: true, IgnoreExtraColumns: true},
+	).(*DSQL)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if got := conn.(*DSQLConn).LastKey(); got != nil {
+		t.Errorf("LastKey - got: %v, want: nil", got)
+	}
+	for i := 0; i < 3; i++ {
+		if !conn.Next() {
+			t.Fatalf("Next - return false early")
+		}
+	}
+	lastKey := conn.(*DSQLConn).LastKey()
+	conn.Close()
+	if lastKey != int64(3) {
+		t.Fatalf("LastKey - got: %v, want: 3", lastKey)
+	}
+
+	conn, err = ds.OpenAfterKey(context.Background(), lastKey)
+	if err != nil {
+		t.Fatalf("OpenAfterKey: %s", err)
+	}
+	defer conn.Close()
+	wantRecords := []ddataset.Record{
+		ddataset.Record{
+			"uid":      dlit.MustNew(4),
+			"username": dlit.MustNew("Mary Terence"),
+		},
+		ddataset.Record{
+			"uid":      dlit.MustNew(5),
+			"username": dlit.MustNew("George Eliot"),
+		},
+	}
+	for _, wantRecord := range wantRecords {
+		if !conn.Next() {
+			t.Fatalf("Next - return false early")
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, wantRecord) {
+			t.Errorf("Read - got: %s, want: %s", record, wantRecord)
+		}
+	}
+	if conn.Next() {
+		t.Errorf("Next - return true, despite having finished")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err: %s", err)
+	}
+}
+
+func TestOpen_paged_errors(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	tableName := "userinfo"
+	fieldNames := []string{"uid", "username", "dept", "started"}
+	cases := []struct {
+		dbHandler DBHandler
+		wantErr   error
+	}{
+		{dbHandler: rowsOnlyHandler{newSqlite3Handler(filename, tableName)},
+			wantErr: errors.New("DBHandler doesn't support pagination"),
+		},
+		{dbHandler: newSqlite3Handler(filename, tableName),
+			wantErr: errors.New("no key column set"),
+		},
+		{dbHandler: dsqlite.NewTableHandler(
+			filename,
+			tableName,
+			dsqlite.Options{KeyColumn: "id"},
+		),
+			wantErr: errors.New("key column doesn't exist: id"),
+		},
+		{dbHandler: dsqlite.NewQueryHandler(
+			filename,
+			"SELECT uid AS id, username, dept, started FROM userinfo",
+			[]interface{}{},
+			dsqlite.Options{KeyColumn: "uid"},
+		),
+			wantErr: errors.New("key column doesn't exist: uid"),
+		},
+	}
+	for i, c := range cases {
+		ds := NewWithOptions(
+			c.dbHandler,
+			ddataset.NewSchema(fieldNames),
+			Options{PageSize: 2},
+		)
+		_, err := ds.Open()
+		if !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) Open() - got err: %v, want: %s", i, err, c.wantErr)
+		}
+	}
+
+	ds := New(newSqlite3Handler(filename, tableName), fieldNames).(*DSQL)
+	wantErr := errors.New("OpenAfterKey requires Options.PageSize to be set")
+	_, err := ds.OpenAfterKey(context.Background(), int64(1))
+	if !testhelpers.ErrorMatch(err, wantErr) {
+		t.Errorf("OpenAfterKey - got err: %v, want: %s", err, wantErr)
+	}
+}
+
+// countPagesHandler counts the number of pages requested from a
+// PageDBHandler
+type countPagesHandler struct {
+	PageDBHandler
+	numPages int
+}
+
+func (h *countPagesHandler) Open() error {
+	return h.PageDBHandler.(DBHandler).Open()
+}
+
+func (h *countPagesHandler) Rows() (*sql.Rows, error) {
+	return h.PageDBHandler.(DBHandler).Rows()
+}
+
+func (h *countPagesHandler) Close() error {
+	return h.PageDBHandler.(DBHandler).Close()
+}
+
+func (h *countPagesHandler) PageRows(
+	ctx context.Context,
+	after interface{},
+	pageSize int,
+) (*sql.Rows, error) {
+	h.numPages++
+	return h.PageDBHandler.PageRows(ctx, after, pageSize)
+}
+
+func TestOpenPushdown(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	tableName := "userinfo"
+	fieldNames := []string{"uid", "username", "dept", "started"}
+	cases := []struct {
+		dbHandler      DBHandler
+		pushdown       ddataset.Pushdown
+		wantRemainder  ddataset.Pushdown
+		wantNumRecords int
+	}{
+		{dbHandler: newSqlite3Handler(filename, tableName),
+			pushdown:       ddataset.Pushdown{},
+			wantRemainder:  ddataset.Pushdown{},
+			wantNumRecords: 5,
+		},
+		{dbHandler: newSqlite3Handler(filename, tableName),
+			pushdown:       ddataset.Pushdown{HasLimit: true, Limit: 2},
+			wantRemainder:  ddataset.Pushdown{},
+			wantNumRecords: 2,
+		},
+		{dbHandler: newSqlite3Handler(filename, tableName),
+			pushdown:       ddataset.Pushdown{HasLimit: true, Limit: -1},
+			wantRemainder:  ddataset.Pushdown{},
+			wantNumRecords: 0,
+		},
+		{dbHandler: NewHandler(
+			"sqlite3",
+			filename,
+			"SELECT * FROM userinfo;",
+			[]interface{}{},
+		),
+			pushdown:       ddataset.Pushdown{HasLimit: true, Limit: 3},
+			wantRemainder:  ddataset.Pushdown{},
+			wantNumRecords: 3,
+		},
+		{dbHandler: rowsOnlyHandler{newSqlite3Handler(filename, tableName)},
+			pushdown:       ddataset.Pushdown{HasLimit: true, Limit: 2},
+			wantRemainder:  ddataset.Pushdown{HasLimit: true, Limit: 2},
+			wantNumRecords: 5,
+		},
+	}
+	for i, c := range cases {
+		ds := New(c.dbHandler, fieldNames)
+		conn, remainder, err :=
+			ddataset.OpenPushdown(context.Background(), ds, c.pushdown)
+		if err != nil {
+			t.Fatalf("(%d) OpenPushdown: %s", i, err)
+		}
+		if remainder != c.wantRemainder {
+			t.Errorf("(%d) OpenPushdown - got remainder: %v, want: %v",
+				i, remainder, c.wantRemainder)
+		}
+		numRecords := 0
+		for conn.Next() {
+			numRecords++
+		}
+		if err := conn.Err(); err != nil {
+			t.Errorf("(%d) Err: %s", i, err)
+		}
+		if numRecords != c.wantNumRecords {
+			t.Errorf("(%d) Next - got records: %d, want: %d",
+				i, numRecords, c.wantNumRecords)
+		}
+		conn.Close()
+	}
+}
+
+func TestOpen_dtruncate(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.db")
+	tableName := "people"
+	fieldNames := []string{
+		"name", "balance", "numCards", "martialStatus",
+		"tertiaryEducated", "success",
+	}
+	h := &recordPushdownHandler{
+		PushdownDBHandler: dsqlite.NewTableHandler(
+			filename,
+			tableName,
+			dsqlite.Options{},
+		),
+	}
+	ds := dtruncate.New(New(h, fieldNames), 20)
+	if got := ds.NumRecords(); got != 20 {
+		t.Errorf("NumRecords - got: %d, want: 20", got)
+	}
+	want := ddataset.Pushdown{HasLimit: true, Limit: 20}
+	if h.pushdown != want {
+		t.Errorf("PushdownRows - got: %v, want: %v", h.pushdown, want)
+	}
+}
+
+// recordPushdownHandler records the Pushdown passed to PushdownRows
+type recordPushdownHandler struct {
+	PushdownDBHandler
+	pushdown ddataset.Pushdown
+}
+
+func (h *recordPushdownHandler) Open() error {
+	return h.PushdownDBHandler.(DBHandler).Open()
+}
+
+func (h *recordPushdownHandler) Rows() (*sql.Rows, error) {
+	return h.PushdownDBHandler.(DBHandler).Rows()
+}
+
+func (h *recordPushdownHandler) Close() error {
+	return h.PushdownDBHandler.(DBHandler).Close()
+}
+
+func (h *recordPushdownHandler) PushdownRows(
+	ctx context.Context,
+	p ddataset.Pushdown,
+) (*sql.Rows, ddataset.Pushdown, error) {
+	h.pushdown = p
+	return h.PushdownDBHandler.PushdownRows(ctx, p)
+}
+
 func TestOpenNextRead_goroutines(t *testing.T) {
 	var numGoroutines int
 	filename := filepath.Join("fixtures", "debt.db")
@@ -269,7 +887,7 @@ func TestOpenNextRead_goroutines(t *testing.T) {
 		"tertiaryEducated",
 		"success",
 	}
-	ds := New(internal.NewSqlite3Handler(filename, tableName, 64), fieldNames)
+	ds := New(newSqlite3Handler(filename, tableName), fieldNames)
 	if testing.Short() {
 		numGoroutines = 10
 	} else {
@@ -302,6 +920,19 @@ func TestOpenNextRead_goroutines(t *testing.T) {
 	}
 }
 
+func TestOpenContext_cancel(t *testing.T) {
+	filename := filepath.Join("fixtures", "debt.db")
+	tableName := "people"
+	fieldNames := []string{
+		"name", "balance", "numCards", "martialStatus",
+		"tertiaryEducated", "success",
+	}
+	ds := New(newSqlite3Handler(filename, tableName), fieldNames)
+	if err := testhelpers.CheckOpenContextCancel(ds, 5); err != nil {
+		t.Errorf("CheckOpenContextCancel: %s", err)
+	}
+}
+
 /*************************
  *  Benchmarks
  *************************/
@@ -317,7 +948,7 @@ func BenchmarkOpenNextRead(b *testing.B) {
 		"tertiaryEducated",
 		"success",
 	}
-	ds := New(internal.NewSqlite3Handler(filename, tableName, 64), fieldNames)
+	ds := New(newSqlite3Handler(filename, tableName), fieldNames)
 	sumBalances := make([]int64, b.N)
 
 	b.ResetTimer()
@@ -347,7 +978,7 @@ func BenchmarkOpenNextRead_goroutines(b *testing.B) {
 		"tertiaryEducated",
 		"success",
 	}
-	ds := New(internal.NewSqlite3Handler(filename, tableName, 64), fieldNames)
+	ds := New(newSqlite3Handler(filename, tableName), fieldNames)
 	sumBalances := make(chan int64, b.N)
 	wg := sync.WaitGroup{}
 	wg.Add(b.N)
@@ -387,7 +1018,7 @@ func BenchmarkNext(b *testing.B) {
 		"tertiaryEducated",
 		"success",
 	}
-	ds := New(internal.NewSqlite3Handler(filename, tableName, 64), fieldNames)
+	ds := New(newSqlite3Handler(filename, tableName), fieldNames)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
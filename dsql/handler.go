@@ -0,0 +1,239 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal"
+)
+
+// ErrHandlerNotOpen indicates that a Handler was used before being opened
+var ErrHandlerNotOpen = errors.New("handler isn't open")
+
+// Handler is a DBHandler which runs a query on a database accessed
+// through any registered database/sql driver.  Rows only runs the query
+// as given, but Count and PushdownRows wrap it in a derived table, as
+// SELECT COUNT(*) FROM (query) AS alias and SELECT * FROM (query) AS
+// alias LIMIT n.  This is understood by SQLite, PostgreSQL and MySQL but
+// not by databases without LIMIT, such as SQL Server and Oracle.  For
+// those, wrap the Handler in a type which only has the methods of
+// DBHandler so that DSQL doesn't use Count or PushdownRows.
+type Handler struct {
+	driverName     string
+	dataSourceName string
+	query          string
+	args           []interface{}
+	db             *sql.DB
+	ownsDB         bool
+	openConn       int
+	mu             sync.Mutex
+}
+
+// NewHandler creates a Handler which opens the database using
+// sql.Open(driverName, dataSourceName) and returns the rows of query
+// run with args as its bind arguments.  The database is opened by
+// the first call to Open and closed once every call to Open has been
+// matched by a call to Close.
+func NewHandler(
+	driverName string,
+	dataSourceName string,
+	query string,
+	args []interface{},
+) *Handler {
+	return &Handler{
+		driverName:     driverName,
+		dataSourceName: dataSourceName,
+		query:          query,
+		args:           args,
+		db:             nil,
+		ownsDB:         true,
+		openConn:       0,
+	}
+}
+
+// NewHandlerFromDB creates a Handler which returns the rows of query
+// run with args as its bind arguments on db.  The Handler never closes
+// db, which remains the responsibility of the caller.
+func NewHandlerFromDB(
+	db *sql.DB,
+	query string,
+	args []interface{},
+) *Handler {
+	return &Handler{
+		query:    query,
+		args:     args,
+		db:       db,
+		ownsDB:   false,
+		openConn: 0,
+	}
+}
+
+// Open opens the database if it isn't already open
+func (h *Handler) Open() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn > 0 || !h.ownsDB {
+		h.openConn++
+		return nil
+	}
+	db, err := sql.Open(h.driverName, h.dataSourceName)
+	if err != nil {
+		return err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+	h.db = db
+	h.openConn = 1
+	return nil
+}
+
+// Close closes the database once every call to Open has been matched
+// by a call to Close
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn < 1 {
+		return nil
+	}
+	h.openConn--
+	if h.openConn == 0 && h.ownsDB {
+		err := h.db.Close()
+		h.db = nil
+		return err
+	}
+	return nil
+}
+
+// Rows returns the rows for the query
+func (h *Handler) Rows() (*sql.Rows, error) {
+	return h.RowsContext(context.Background())
+}
+
+// RowsContext returns the rows for the query using ctx
+func (h *Handler) RowsContext(ctx context.Context) (*sql.Rows, error) {
+	db, err := h.openDB()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, h.query, h.args...)
+}
+
+// PushdownRows returns the rows for the query using ctx with as much
+// of p applied as possible.  It returns a copy of p with the parts that
+// were applied cleared.
+func (h *Handler) PushdownRows(
+	ctx context.Context,
+	p ddataset.Pushdown,
+) (*sql.Rows, ddataset.Pushdown, error) {
+	db, err := h.openDB()
+	if err != nil {
+		return nil, p, err
+	}
+	query, remainder := internal.PushdownQuery(h.query, p)
+	rows, err := db.QueryContext(ctx, query, h.args...)
+	return rows, remainder, err
+}
+
+// Count returns the number of rows returned by the query using
+// SELECT COUNT(*)
+func (h *Handler) Count() (int64, error) {
+	db, err := h.openDB()
+	if err != nil {
+		return 0, err
+	}
+	var numRows int64
+	err = db.QueryRow(internal.CountQuery(h.query), h.args...).Scan(&numRows)
+	return numRows, err
+}
+
+// Columns returns the names of the columns returned by the query.
+// These can be used as the field names for New.
+func (h *Handler) Columns() ([]string, error) {
+	if err := h.Open(); err != nil {
+		return nil, err
+	}
+	defer h.Close()
+	rows, err := h.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// openDB returns the database if the Handler is open
+func (h *Handler) openDB() (*sql.DB, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn < 1 {
+		return nil, ErrHandlerNotOpen
+	}
+	return h.db, nil
+}
+
+// PageHandler is a Handler which implements PageDBHandler so that its
+// rows can be read in pages ordered by a key column
+type PageHandler struct {
+	*Handler
+	keyColumn   string
+	placeholder func(n int) string
+}
+
+// NewPageHandler creates a PageHandler which returns the rows of h in
+// pages ordered by keyColumn.  The values of keyColumn must be unique and
+// not null.  placeholder returns the bind parameter placeholder for the
+// nth argument of the query, counting from 1.  If it is nil then ? is
+// used, otherwise for PostgreSQL you might return "$n".  Each page is
+// read using SELECT * FROM (query) AS alias WHERE "keyColumn" > after
+// ORDER BY "keyColumn" LIMIT pageSize, so as well as LIMIT the database
+// must accept ANSI double quoted identifiers, which MySQL only does in
+// ANSI_QUOTES mode.
+func NewPageHandler(
+	h *Handler,
+	keyColumn string,
+	placeholder func(n int) string,
+) *PageHandler {
+	if placeholder == nil {
+		placeholder = func(int) string { return "?" }
+	}
+	return &PageHandler{
+		Handler:     h,
+		keyColumn:   keyColumn,
+		placeholder: placeholder,
+	}
+}
+
+// KeyColumn returns the name of the column that pages are ordered by
+func (h *PageHandler) KeyColumn() string {
+	return h.keyColumn
+}
+
+// PageRows returns up to pageSize rows of the query, ordered by the key
+// column, whose key is greater than after.  If after is nil then the rows
+// start from the first row.
+func (h *PageHandler) PageRows(
+	ctx context.Context,
+	after interface{},
+	pageSize int,
+) (*sql.Rows, error) {
+	db, err := h.openDB()
+	if err != nil {
+		return nil, err
+	}
+	args := h.args
+	afterPlaceholder := ""
+	if after != nil {
+		args = append(append([]interface{}{}, h.args...), after)
+		afterPlaceholder = h.placeholder(len(args))
+	}
+	query := internal.PageQuery(h.query, h.keyColumn, afterPlaceholder, pageSize)
+	return db.QueryContext(ctx, query, args...)
+}
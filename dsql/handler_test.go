@@ -0,0 +1,369 @@
+//go:build !nosqlite3
+// +build !nosqlite3
+
+package dsql
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandlerRead(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	query := "SELECT uid, username FROM userinfo WHERE dept = ? ORDER BY uid"
+	wantFieldNames := []string{"uid", "username"}
+	wantRecords := []ddataset.Record{
+		ddataset.Record{
+			"uid":      dlit.MustNew(1),
+			"username": dlit.MustNew("Fred Wilkins"),
+		},
+		ddataset.Record{
+			"uid":      dlit.MustNew(2),
+			"username": dlit.MustNew("Bob Field"),
+		},
+	}
+	db, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+
+	handlers := []*Handler{
+		NewHandler("sqlite3", filename, query, []interface{}{"Logistics"}),
+		NewHandlerFromDB(db, query, []interface{}{"Logistics"}),
+	}
+	for i, h := range handlers {
+		fieldNames, err := h.Columns()
+		if err != nil {
+			t.Fatalf("(%d) Columns: %s", i, err)
+		}
+		if !reflect.DeepEqual(fieldNames, wantFieldNames) {
+			t.Errorf("(%d) Columns - got: %s, want: %s",
+				i, fieldNames, wantFieldNames)
+		}
+		ds := New(h, fieldNames)
+		conn, err := ds.Open()
+		if err != nil {
+			t.Fatalf("(%d) Open: %s", i, err)
+		}
+		for _, wantRecord := range wantRecords {
+			if !conn.Next() {
+				t.Fatalf("(%d) Next() - return false early", i)
+			}
+			record := conn.Read()
+			if !testhelpers.MatchRecords(record, wantRecord) {
+				t.Errorf("(%d) Read() got: %s, want: %s", i, record, wantRecord)
+			}
+		}
+		if conn.Next() {
+			t.Errorf("(%d) Next() - return true, despite having finished", i)
+		}
+		if err := conn.Err(); err != nil {
+			t.Errorf("(%d) Err() err: %s", i, err)
+		}
+		conn.Close()
+	}
+
+	if err := db.Ping(); err != nil {
+		t.Errorf("db.Ping - handler closed database it doesn't own: %s", err)
+	}
+}
+
+func TestHandlerOpen_errors(t *testing.T) {
+	h := NewHandler("nodriver", "", "SELECT 1", []interface{}{})
+	wantErr := "sql: unknown driver \"nodriver\" (forgotten import?)"
+	if err := h.Open(); err == nil || err.Error() != wantErr {
+		t.Errorf("Open - got err: %v, want: %s", err, wantErr)
+	}
+}
+
+func TestHandlerRows_errors(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	h := NewHandler("sqlite3", filename, "SELECT * FROM userinfo", []interface{}{})
+	if _, err := h.Rows(); err != ErrHandlerNotOpen {
+		t.Errorf("Rows - got err: %v, want: %s", err, ErrHandlerNotOpen)
+	}
+}
+
+func TestHandlerCount(t *testing.T) {
+	cases := []struct {
+		filename string
+		query    string
+		args     []interface{}
+		want     int64
+	}{
+		{filename: filepath.Join("fixtures", "users.db"),
+			query: "SELECT * FROM userinfo",
+			args:  []interface{}{},
+			want:  5,
+		},
+		{filename: filepath.Join("fixtures", "users.db"),
+			query: "SELECT uid FROM userinfo WHERE dept = ?;",
+			args:  []interface{}{"Shipping"},
+			want:  2,
+		},
+		{filename: filepath.Join("fixtures", "debt.db"),
+			query: "SELECT * FROM people",
+			args:  []interface{}{},
+			want:  10000,
+		},
+	}
+	for i, c := range cases {
+		h := NewHandler("sqlite3", c.filename, c.query, c.args)
+		if _, err := h.Count(); err != ErrHandlerNotOpen {
+			t.Errorf("(%d) Count - got err: %v, want: %s",
+				i, err, ErrHandlerNotOpen)
+		}
+		fieldNames, err := h.Columns()
+		if err != nil {
+			t.Fatalf("(%d) Columns: %s", i, err)
+		}
+		ds := New(h, fieldNames)
+		if got := ds.NumRecords(); got != c.want {
+			t.Errorf("(%d) NumRecords - got: %d, want: %d", i, got, c.want)
+		}
+	}
+}
+
+func TestPageHandlerRead(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	query := "SELECT uid, username FROM userinfo WHERE dept = ?"
+	wantRecords := []ddataset.Record{
+		ddataset.Record{
+			"uid":      dlit.MustNew(3),
+			"username": dlit.MustNew("Ned James"),
+		},
+		ddataset.Record{
+			"uid":      dlit.MustNew(4),
+			"username": dlit.MustNew("Mary Terence"),
+		},
+	}
+	h := NewPageHandler(
+		NewHandler("sqlite3", filename, query, []interface{}{"Shipping"}),
+		"uid",
+		nil,
+	)
+	ds := NewWithOptions(
+		h,
+		ddataset.NewSchema([]string{"uid", "username"}),
+		Options{PageSize: 1},
+	)
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	for _, wantRecord := range wantRecords {
+		if !conn.Next() {
+			t.Fatalf("Next() - return false early")
+		}
+		record := conn.Read()
+		if !testhelpers.MatchRecords(record, wantRecord) {
+			t.Errorf("Read() got: %s, want: %s", record, wantRecord)
+		}
+	}
+	if conn.Next() {
+		t.Errorf("Next() - return true, despite having finished")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err() err: %s", err)
+	}
+}
+
+func TestHandlerOpenClose_goroutines(t *testing.T) {
+	var numGoroutines int
+	filename := filepath.Join("fixtures", "debt.db")
+	query := "SELECT * FROM people"
+	fieldNames := []string{
+		"name",
+		"balance",
+		"numCards",
+		"martialStatus",
+		"tertiaryEducated",
+		"success",
+	}
+	if testing.Short() {
+		numGoroutines = 10
+	} else {
+		numGoroutines = 100
+	}
+	h := NewHandler("sqlite3", filename, query, []interface{}{})
+	ds := New(h, fieldNames)
+	sumBalances := make(chan int64, numGoroutines)
+	wg := sync.WaitGroup{}
+	wg.Add(numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			sumBalances <- testhelpers.SumBalance(ds)
+		}()
+	}
+	wg.Wait()
+	close(sumBalances)
+
+	sumBalance := <-sumBalances
+	for sum := range sumBalances {
+		if sumBalance != sum {
+			t.Fatal("sumBalances are not all equal")
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn != 0 || h.db != nil {
+		t.Errorf("handler still open - openConn: %d, db: %v", h.openConn, h.db)
+	}
+}
+
+func TestOpenClose_goroutines(t *testing.T) {
+	var numGoroutines int
+	filename := filepath.Join("fixtures", "debt.db")
+	h := NewHandler("sqlite3", filename, "SELECT * FROM people", []interface{}{})
+	fieldNames := []string{
+		"name", "balance", "numCards", "martialStatus",
+		"tertiaryEducated", "success",
+	}
+	if testing.Short() {
+		numGoroutines = 10
+	} else {
+		numGoroutines = 100
+	}
+	ds := New(h, fieldNames)
+	wg := sync.WaitGroup{}
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func(stopRow int) {
+			defer wg.Done()
+			conn, err := ds.Open()
+			if err != nil {
+				t.Errorf("Open: %s", err)
+				return
+			}
+			for j := 0; j < stopRow && conn.Next(); j++ {
+				conn.Read()
+			}
+			if err := conn.Close(); err != nil {
+				t.Errorf("Close: %s", err)
+			}
+			if err := conn.Close(); err != nil {
+				t.Errorf("Close - second time: %s", err)
+			}
+			if conn.Next() {
+				t.Errorf("Next - returned true after Close")
+			}
+			if err := conn.Err(); err != ddataset.ErrConnClosed {
+				t.Errorf("Err - got: %v, want: %s", err, ddataset.ErrConnClosed)
+			}
+		}(i * 50)
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn != 0 || h.db != nil {
+		t.Errorf("handler still open - openConn: %d, db: %v", h.openConn, h.db)
+	}
+}
+
+func TestRelease_openConns(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	h := NewHandler(
+		"sqlite3",
+		filename,
+		"SELECT * FROM userinfo",
+		[]interface{}{},
+	)
+	fieldNames := []string{"uid", "username", "dept", "started"}
+	ds := New(h, fieldNames)
+	conns := make([]ddataset.Conn, 3)
+	for i := range conns {
+		conn, err := ds.Open()
+		if err != nil {
+			t.Fatalf("Open: %s", err)
+		}
+		if !conn.Next() {
+			t.Fatalf("Next - returned false early")
+		}
+		conns[i] = conn
+	}
+	if err := ds.Release(); err != nil {
+		t.Fatalf("Release: %s", err)
+	}
+	for i, conn := range conns {
+		if conn.Next() {
+			t.Errorf("(%d) Next - returned true after Release", i)
+		}
+		if err := conn.Err(); err != ddataset.ErrReleased {
+			t.Errorf("(%d) Err - got: %v, want: %s", i, err, ddataset.ErrReleased)
+		}
+		if err := conn.Close(); err != nil {
+			t.Errorf("(%d) Close: %s", i, err)
+		}
+	}
+	if _, err := ds.Open(); err != ddataset.ErrReleased {
+		t.Errorf("Open - got err: %v, want: %s", err, ddataset.ErrReleased)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn != 0 || h.db != nil {
+		t.Errorf("handler still open - openConn: %d, db: %v", h.openConn, h.db)
+	}
+}
+
+func TestRelease_goroutines(t *testing.T) {
+	var numGoroutines int
+	filename := filepath.Join("fixtures", "debt.db")
+	h := NewHandler("sqlite3", filename, "SELECT * FROM people", []interface{}{})
+	fieldNames := []string{
+		"name", "balance", "numCards", "martialStatus",
+		"tertiaryEducated", "success",
+	}
+	if testing.Short() {
+		numGoroutines = 10
+	} else {
+		numGoroutines = 100
+	}
+	ds := New(h, fieldNames)
+	opened := sync.WaitGroup{}
+	opened.Add(numGoroutines)
+	wg := sync.WaitGroup{}
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			conn, err := ds.Open()
+			opened.Done()
+			if err != nil {
+				t.Errorf("Open: %s", err)
+				return
+			}
+			defer conn.Close()
+			for conn.Next() {
+				conn.Read()
+			}
+			if err := conn.Err(); err != nil && err != ddataset.ErrReleased {
+				t.Errorf("Err - got: %s, want: nil or %s", err, ddataset.ErrReleased)
+			}
+		}()
+	}
+	opened.Wait()
+	if err := ds.Release(); err != nil {
+		t.Errorf("Release: %s", err)
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn != 0 || h.db != nil {
+		t.Errorf("handler still open - openConn: %d, db: %v", h.openConn, h.db)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dsql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal"
+	"github.com/lawrencewoodman/dlit"
+)
+
+// DefaultBatchSize is the number of records inserted in each
+// transaction by Import if ImportOptions.BatchSize isn't set
+const DefaultBatchSize = 1000
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	// BatchSize is the number of records inserted in each transaction.
+	// If it is less than 1 then DefaultBatchSize is used.
+	BatchSize int
+	// Placeholder returns the bind parameter placeholder for the nth
+	// value of an INSERT statement, counting from 1.  If it is nil then
+	// ? is used, otherwise for PostgreSQL you might return "$n".
+	Placeholder func(n int) string
+}
+
+// Import creates a table called tableName in db and inserts every record
+// of ds into it, returning the number of records inserted.  The columns of
+// the table are named after ds.Fields() and, if ds implements
+// ddataset.SchemaDataset, given an SQL type based on the Kind of each field.
+// Values of KindTime fields are inserted as a time.Time, so they must be
+// in RFC 3339 format or use the layout 2006-01-02 15:04:05 or 2006-01-02.
+// Records are inserted in transactions of options.BatchSize records.  If
+// there is an error, including from the connection to ds, the current
+// transaction is rolled back and the number of records already committed
+// is returned with the error.
+func Import(
+	ds ddataset.Dataset,
+	db *sql.DB,
+	tableName string,
+	options ImportOptions,
+) (int64, error) {
+	batchSize := options.BatchSize
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+	placeholder := options.Placeholder
+	if placeholder == nil {
+		placeholder = func(int) string { return "?" }
+	}
+	schema := ddataset.SchemaOf(ds)
+
+	conn, err := ds.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if _, err := db.Exec(createTableQuery(tableName, schema)); err != nil {
+		return 0, err
+	}
+
+	insertQuery := insertQuery(tableName, schema, placeholder)
+	numCommitted := int64(0)
+	values := make([]interface{}, len(schema))
+	for {
+		tx, err := db.Begin()
+		if err != nil {
+			return numCommitted, err
+		}
+		stmt, err := tx.Prepare(insertQuery)
+		if err != nil {
+			tx.Rollback()
+			return numCommitted, err
+		}
+		numInBatch := 0
+		for numInBatch < batchSize && conn.Next() {
+			record := conn.Read()
+			for i, field := range schema {
+				v, err := importValue(field, record[field.Name])
+				if err != nil {
+					stmt.Close()
+					tx.Rollback()
+					return numCommitted, err
+				}
+				values[i] = v
+			}
+			if _, err := stmt.Exec(values...); err != nil {
+				stmt.Close()
+				tx.Rollback()
+				return numCommitted, err
+			}
+			numInBatch++
+		}
+		stmt.Close()
+		if err := conn.Err(); err != nil {
+			tx.Rollback()
+			return numCommitted, err
+		}
+		if err := tx.Commit(); err != nil {
+			return numCommitted, err
+		}
+		numCommitted += int64(numInBatch)
+		if numInBatch < batchSize {
+			return numCommitted, nil
+		}
+	}
+}
+
+// createTableQuery returns a CREATE TABLE statement for schema
+func createTableQuery(tableName string, schema ddataset.Schema) string {
+	columns := make([]string, len(schema))
+	for i, field := range schema {
+		columns[i] = internal.QuoteIdentifier(field.Name) + " " + sqlType(field.Kind)
+	}
+	return fmt.Sprintf(
+		"CREATE TABLE %s (%s)",
+		internal.QuoteIdentifier(tableName),
+		strings.Join(columns, ", "),
+	)
+}
+
+// insertQuery returns an INSERT statement for schema
+func insertQuery(
+	tableName string,
+	schema ddataset.Schema,
+	placeholder func(int) string,
+) string {
+	columns := make([]string, len(schema))
+	placeholders := make([]string, len(schema))
+	for i, field := range schema {
+		columns[i] = internal.QuoteIdentifier(field.Name)
+		placeholders[i] = placeholder(i + 1)
+	}
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		internal.QuoteIdentifier(tableName),
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "),
+	)
+}
+
+// sqlType returns the SQL column type used for a Kind
+func sqlType(kind ddataset.Kind) string {
+	switch kind {
+	case ddataset.KindInt:
+		return "INTEGER"
+	case ddataset.KindFloat:
+		return "DOUBLE PRECISION"
+	case ddataset.KindBool:
+		return "BOOLEAN"
+	case ddataset.KindTime:
+		return "TIMESTAMP"
+	}
+	return "TEXT"
+}
+
+// importValue returns the value to insert for l based on the Kind of
+// field.  Empty values of fields which aren't strings are inserted as NULL.
+func importValue(field ddataset.Field, l *dlit.Literal) (interface{}, error) {
+	if ddataset.IsNull(l) {
+		return nil, nil
+	}
+	if l.String() == "" && field.Kind != ddataset.KindString &&
+		field.Kind != ddataset.KindUnknown {
+		return nil, nil
+	}
+	switch field.Kind {
+	case ddataset.KindInt:
+		if v, ok := l.Int(); ok {
+			return v, nil
+		}
+	case ddataset.KindFloat:
+		if v, ok := l.Float(); ok {
+			return v, nil
+		}
+	case ddataset.KindBool:
+		if v, ok := l.Bool(); ok {
+			return v, nil
+		}
+	case ddataset.KindTime:
+		if v, ok := internal.ParseTime(l.String()); ok {
+			return v, nil
+		}
+	default:
+		return l.String(), nil
+	}
+	return nil, fmt.Errorf(
+		"can't import value of field: %s, as %s: %s",
+		field.Name, field.Kind, l,
+	)
+}
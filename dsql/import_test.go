@@ -0,0 +1,277 @@
+//go:build !nosqlite3
+// +build !nosqlite3
+
+package dsql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestImport(t *testing.T) {
+	schema := ddataset.Schema{
+		{Name: "uid", Kind: ddataset.KindInt},
+		{Name: "username", Kind: ddataset.KindString},
+		{Name: "dept", Kind: ddataset.KindString, Nullable: true},
+		{Name: "started", Kind: ddataset.KindString},
+	}
+	wantTypes := []string{"integer", "text", "text", "text"}
+	for _, batchSize := range []int{0, 1, 2, 5, 6} {
+		tmpDir, err := ioutil.TempDir("", "dsql_import")
+		if err != nil {
+			t.Fatalf("TempDir: %s", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "import.db"))
+		if err != nil {
+			t.Fatalf("sql.Open: %s", err)
+		}
+		defer db.Close()
+
+		ds := NewWithSchema(
+			newSqlite3Handler(filepath.Join("fixtures", "users.db"), "userinfo"),
+			schema,
+		)
+		got, err := Import(ds, db, "users", ImportOptions{BatchSize: batchSize})
+		if err != nil {
+			t.Fatalf("(batchSize: %d) Import: %s", batchSize, err)
+		}
+		if got != 5 {
+			t.Errorf("(batchSize: %d) Import - got: %d, want: 5", batchSize, got)
+		}
+
+		ids := NewWithSchema(
+			NewHandlerFromDB(db, "SELECT * FROM users", []interface{}{}),
+			schema,
+		)
+		if err := testhelpers.CheckDatasetsEqual(ds, ids); err != nil {
+			t.Errorf("(batchSize: %d) CheckDatasetsEqual: %s", batchSize, err)
+		}
+
+		for i, field := range schema {
+			var gotType string
+			err := db.QueryRow(
+				"SELECT typeof(" + internal.QuoteIdentifier(field.Name) +
+					") FROM users WHERE uid = 1",
+			).Scan(&gotType)
+			if err != nil {
+				t.Fatalf("QueryRow: %s", err)
+			}
+			if gotType != wantTypes[i] {
+				t.Errorf("(batchSize: %d) field: %s, got type: %s, want: %s",
+					batchSize, field.Name, gotType, wantTypes[i])
+			}
+		}
+	}
+}
+
+func TestImport_time(t *testing.T) {
+	schema := ddataset.Schema{
+		{Name: "uid", Kind: ddataset.KindInt},
+		{Name: "username", Kind: ddataset.KindString},
+		{Name: "dept", Kind: ddataset.KindString, Nullable: true},
+		{Name: "started", Kind: ddataset.KindTime},
+	}
+	tmpDir, err := ioutil.TempDir("", "dsql_import")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "import.db"))
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+	ds := NewWithSchema(
+		newSqlite3Handler(filepath.Join("fixtures", "users.db"), "userinfo"),
+		schema,
+	)
+	if _, err := Import(ds, db, "users", ImportOptions{}); err != nil {
+		t.Fatalf("Import: %s", err)
+	}
+	var got time.Time
+	err = db.QueryRow("SELECT started FROM users WHERE uid = 1").Scan(&got)
+	if err != nil {
+		t.Fatalf("QueryRow: %s", err)
+	}
+	want := time.Date(2013, 10, 5, 10, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("started - got: %s, want: %s", got, want)
+	}
+}
+
+func TestImportValue_time(t *testing.T) {
+	field := ddataset.Field{Name: "started", Kind: ddataset.KindTime}
+	cases := []struct {
+		in   string
+		want time.Time
+	}{
+		{in: "2013-10-05", want: time.Date(2013, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{in: "2013-10-05 10:00:00",
+			want: time.Date(2013, 10, 5, 10, 0, 0, 0, time.UTC),
+		},
+		{in: "2013-10-05T10:00:00+01:00",
+			want: time.Date(2013, 10, 5, 9, 0, 0, 0, time.UTC),
+		},
+	}
+	for i, c := range cases {
+		got, err := importValue(field, dlit.NewString(c.in))
+		if err != nil {
+			t.Errorf("(%d) importValue: %s", i, err)
+			continue
+		}
+		if gotTime, ok := got.(time.Time); !ok || !gotTime.Equal(c.want) {
+			t.Errorf("(%d) importValue - got: %v, want: %s", i, got, c.want)
+		}
+	}
+	wantErr := errors.New("can't import value of field: started, as time: soon")
+	_, err := importValue(field, dlit.NewString("soon"))
+	if !testhelpers.ErrorMatch(err, wantErr) {
+		t.Errorf("importValue - got err: %v, want: %s", err, wantErr)
+	}
+}
+
+func TestCreateTableQuery(t *testing.T) {
+	schema := ddataset.Schema{
+		{Name: "name", Kind: ddataset.KindString},
+		{Name: "balance", Kind: ddataset.KindInt},
+		{Name: "rate", Kind: ddataset.KindFloat},
+		{Name: "tertiary_educated", Kind: ddataset.KindBool},
+		{Name: "started", Kind: ddataset.KindTime},
+		{Name: "note \"x\"", Kind: ddataset.KindUnknown},
+	}
+	want := "CREATE TABLE \"people\" (\"name\" TEXT, \"balance\" INTEGER, " +
+		"\"rate\" DOUBLE PRECISION, \"tertiary_educated\" BOOLEAN, " +
+		"\"started\" TIMESTAMP, \"note \"\"x\"\"\" TEXT)"
+	if got := createTableQuery("people", schema); got != want {
+		t.Errorf("createTableQuery - got: %s, want: %s", got, want)
+	}
+}
+
+func TestInsertQuery(t *testing.T) {
+	schema := ddataset.NewSchema([]string{"name", "balance"})
+	placeholder := func(n int) string { return fmt.Sprintf("$%d", n) }
+	want := "INSERT INTO \"people\" (\"name\", \"balance\") VALUES ($1, $2)"
+	if got := insertQuery("people", schema, placeholder); got != want {
+		t.Errorf("insertQuery - got: %s, want: %s", got, want)
+	}
+}
+
+func TestImport_errors(t *testing.T) {
+	wantErr := errors.New("can't read record")
+	ds := &errDataset{numRecords: 3, err: wantErr}
+	tmpDir, err := ioutil.TempDir("", "dsql_import")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "import.db"))
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+
+	got, err := Import(ds, db, "numbers", ImportOptions{BatchSize: 2})
+	if err != wantErr {
+		t.Errorf("Import - got err: %v, want: %s", err, wantErr)
+	}
+	if got != 2 {
+		t.Errorf("Import - got: %d, want: 2", got)
+	}
+	var numRows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM numbers").Scan(&numRows); err != nil {
+		t.Fatalf("QueryRow: %s", err)
+	}
+	if numRows != 2 {
+		t.Errorf("numRows - got: %d, want: 2", numRows)
+	}
+
+	_, err = Import(ds, db, "numbers", ImportOptions{})
+	wantErr = errors.New("table \"numbers\" already exists")
+	if !testhelpers.ErrorMatch(err, wantErr) {
+		t.Errorf("Import - got err: %v, want: %s", err, wantErr)
+	}
+
+	// The table isn't created if the Dataset can't be opened
+	wantErr = errors.New("can't open")
+	ds = &errDataset{openErr: wantErr}
+	if _, err := Import(ds, db, "opened", ImportOptions{}); err != wantErr {
+		t.Errorf("Import - got err: %v, want: %s", err, wantErr)
+	}
+	var numTables int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='opened'",
+	).Scan(&numTables)
+	if err != nil {
+		t.Fatalf("QueryRow: %s", err)
+	}
+	if numTables != 0 {
+		t.Errorf("Import - created table: opened")
+	}
+}
+
+// errDataset returns numRecords records and then err.  If openErr is
+// set then Open returns it instead.
+type errDataset struct {
+	numRecords int
+	err        error
+	openErr    error
+}
+
+type errDatasetConn struct {
+	dataset   *errDataset
+	recordNum int
+	err       error
+}
+
+func (d *errDataset) Open() (ddataset.Conn, error) {
+	if d.openErr != nil {
+		return nil, d.openErr
+	}
+	return &errDatasetConn{dataset: d}, nil
+}
+
+func (d *errDataset) Fields() []string {
+	return []string{"n"}
+}
+
+func (d *errDataset) NumRecords() int64 {
+	return -1
+}
+
+func (d *errDataset) Release() error {
+	return nil
+}
+
+func (c *errDatasetConn) Next() bool {
+	if c.recordNum >= c.dataset.numRecords {
+		c.err = c.dataset.err
+		return false
+	}
+	c.recordNum++
+	return true
+}
+
+func (c *errDatasetConn) Err() error {
+	return c.err
+}
+
+func (c *errDatasetConn) Read() ddataset.Record {
+	return ddataset.Record{"n": dlit.MustNew(c.recordNum)}
+}
+
+func (c *errDatasetConn) Close() error {
+	return nil
+}
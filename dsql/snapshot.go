@@ -0,0 +1,206 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package dsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal"
+)
+
+// ErrSnapshotInUse indicates that a SnapshotHandler can't end its
+// snapshot because it is still open
+var ErrSnapshotInUse = errors.New("snapshot is in use")
+
+// SnapshotHandler is a DBHandler which runs its query inside a single
+// read-only transaction.  Every connection opened on a Dataset using it,
+// and every call to NumRecords, sees the same snapshot of the database
+// until End is called.  This depends on the database giving consistent
+// reads within a transaction, such as SQLite in WAL mode or PostgreSQL
+// with sql.LevelRepeatableRead.  Both of these only take the snapshot
+// when the first query is run in the transaction, rather than when it
+// is begun by Open, so changes made between Open and the first call to
+// Rows or Count are seen.  For more than one connection to be open at
+// once the driver must allow more than one query at a time on a
+// database connection.
+type SnapshotHandler struct {
+	db        *sql.DB
+	query     string
+	args      []interface{}
+	isolation sql.IsolationLevel
+	tx        *sql.Tx
+	cancelTx  context.CancelFunc
+	openConn  int
+	mu        sync.Mutex
+}
+
+// NewSnapshotHandler creates a SnapshotHandler which returns the rows of
+// query run with args as its bind arguments on db.  The transaction is
+// begun by the first call to Open with the given isolation level.  The
+// read-only option and isolation level are passed to the driver, which
+// may ignore them, as go-sqlite3 does, or make Open return an error if it
+// doesn't support them.  The Handler never closes db, which remains the
+// responsibility of the caller.
+func NewSnapshotHandler(
+	db *sql.DB,
+	query string,
+	args []interface{},
+	isolation sql.IsolationLevel,
+) *SnapshotHandler {
+	return &SnapshotHandler{
+		db:        db,
+		query:     query,
+		args:      args,
+		isolation: isolation,
+		tx:        nil,
+		openConn:  0,
+	}
+}
+
+// Open begins the read-only transaction if there isn't one already
+func (h *SnapshotHandler) Open() error {
+	return h.OpenContext(context.Background())
+}
+
+// OpenContext is like Open but ctx can cancel or time out beginning the
+// transaction.  Once begun, the transaction isn't ended when ctx is done,
+// so that it can be used by later calls to Open until End is called.
+func (h *SnapshotHandler) OpenContext(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tx == nil {
+		tx, cancelTx, err := h.beginTx(ctx)
+		if err != nil {
+			return err
+		}
+		h.tx = tx
+		h.cancelTx = cancelTx
+	}
+	h.openConn++
+	return nil
+}
+
+// Close matches a call to Open.  It doesn't end the transaction, so
+// that the next call to Open sees the same snapshot.
+func (h *SnapshotHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn >= 1 {
+		h.openConn--
+	}
+	return nil
+}
+
+// End ends the transaction so that the next call to Open sees a new
+// snapshot of the database.  It returns ErrSnapshotInUse if any call to
+// Open hasn't been matched by a call to Close.
+func (h *SnapshotHandler) End() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn > 0 {
+		return ErrSnapshotInUse
+	}
+	if h.tx == nil {
+		return nil
+	}
+	err := h.tx.Rollback()
+	h.cancelTx()
+	h.tx = nil
+	h.cancelTx = nil
+	return err
+}
+
+// Rows returns the rows for the query
+func (h *SnapshotHandler) Rows() (*sql.Rows, error) {
+	return h.RowsContext(context.Background())
+}
+
+// RowsContext returns the rows for the query using ctx
+func (h *SnapshotHandler) RowsContext(ctx context.Context) (*sql.Rows, error) {
+	tx, err := h.openTx()
+	if err != nil {
+		return nil, err
+	}
+	return tx.QueryContext(ctx, h.query, h.args...)
+}
+
+// PushdownRows returns the rows for the query using ctx with as much
+// of p applied as possible.  It returns a copy of p with the parts that
+// were applied cleared.
+func (h *SnapshotHandler) PushdownRows(
+	ctx context.Context,
+	p ddataset.Pushdown,
+) (*sql.Rows, ddataset.Pushdown, error) {
+	tx, err := h.openTx()
+	if err != nil {
+		return nil, p, err
+	}
+	query, remainder := internal.PushdownQuery(h.query, p)
+	rows, err := tx.QueryContext(ctx, query, h.args...)
+	return rows, remainder, err
+}
+
+// Count returns the number of rows returned by the query using
+// SELECT COUNT(*)
+func (h *SnapshotHandler) Count() (int64, error) {
+	tx, err := h.openTx()
+	if err != nil {
+		return 0, err
+	}
+	var numRows int64
+	err = tx.QueryRow(internal.CountQuery(h.query), h.args...).Scan(&numRows)
+	return numRows, err
+}
+
+// beginTx begins the read-only transaction.  The BEGIN is cancelled if
+// ctx is done before it returns, but the transaction uses its own context
+// so that it isn't rolled back when ctx is done later.  The returned
+// CancelFunc must be called once the transaction has ended.
+func (h *SnapshotHandler) beginTx(
+	ctx context.Context,
+) (*sql.Tx, context.CancelFunc, error) {
+	txCtx, cancelTx := context.WithCancel(context.Background())
+	begun := make(chan struct{})
+	cancelled := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelTx()
+			cancelled <- true
+		case <-begun:
+			cancelled <- false
+		}
+	}()
+	tx, err := h.db.BeginTx(
+		txCtx,
+		&sql.TxOptions{Isolation: h.isolation, ReadOnly: true},
+	)
+	close(begun)
+	if <-cancelled {
+		if err == nil {
+			tx.Rollback()
+		}
+		cancelTx()
+		return nil, nil, ctx.Err()
+	}
+	if err != nil {
+		cancelTx()
+		return nil, nil, err
+	}
+	return tx, cancelTx, nil
+}
+
+// openTx returns the transaction if the Handler is open
+func (h *SnapshotHandler) openTx() (*sql.Tx, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn < 1 {
+		return nil, ErrHandlerNotOpen
+	}
+	return h.tx, nil
+}
@@ -0,0 +1,159 @@
+//go:build !nosqlite3
+// +build !nosqlite3
+
+package dsql
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lawrencewoodman/ddataset"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSnapshotHandler(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dsql_snapshot")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dsn := "file:" + filepath.Join(tmpDir, "snapshot.db") +
+		"?_journal_mode=WAL&_busy_timeout=5000"
+	writer, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer writer.Close()
+	for _, query := range []string{
+		"CREATE TABLE nums (n INTEGER)",
+		"INSERT INTO nums (n) VALUES (1), (2), (3)",
+	} {
+		if _, err := writer.Exec(query); err != nil {
+			t.Fatalf("Exec: %s", err)
+		}
+	}
+	reader, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer reader.Close()
+
+	h := NewSnapshotHandler(
+		reader,
+		"SELECT n FROM nums",
+		[]interface{}{},
+		sql.LevelDefault,
+	)
+	if _, err := h.Rows(); err != ErrHandlerNotOpen {
+		t.Errorf("Rows - got err: %v, want: %s", err, ErrHandlerNotOpen)
+	}
+	ds := New(h, []string{"n"})
+	if got := ds.NumRecords(); got != 3 {
+		t.Errorf("NumRecords - got: %d, want: 3", got)
+	}
+	if _, err := writer.Exec("INSERT INTO nums (n) VALUES (4)"); err != nil {
+		t.Fatalf("Exec: %s", err)
+	}
+	if got := ds.NumRecords(); got != 3 {
+		t.Errorf("NumRecords - got: %d, want: 3", got)
+	}
+	if got := sumNums(t, ds); got != 6 {
+		t.Errorf("sumNums - got: %d, want: 6", got)
+	}
+
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if err := h.End(); err != ErrSnapshotInUse {
+		t.Errorf("End - got err: %v, want: %s", err, ErrSnapshotInUse)
+	}
+	conn.Close()
+
+	if err := h.End(); err != nil {
+		t.Fatalf("End: %s", err)
+	}
+	if got := ds.NumRecords(); got != 4 {
+		t.Errorf("NumRecords - got: %d, want: 4", got)
+	}
+	if got := sumNums(t, ds); got != 10 {
+		t.Errorf("sumNums - got: %d, want: 10", got)
+	}
+	if err := h.End(); err != nil {
+		t.Errorf("End: %s", err)
+	}
+}
+
+// sumNums returns the sum of field n for every record in ds
+func sumNums(t *testing.T, ds ddataset.Dataset) int64 {
+	conn, err := ds.Open()
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer conn.Close()
+	sum := int64(0)
+	for conn.Next() {
+		n, ok := conn.Read()["n"].Int()
+		if !ok {
+			t.Fatalf("Read - n isn't an int: %s", conn.Read()["n"])
+		}
+		sum += n
+	}
+	if err := conn.Err(); err != nil {
+		t.Fatalf("Err: %s", err)
+	}
+	return sum
+}
+
+func TestSnapshotHandler_context(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join("fixtures", "users.db"))
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	h := NewSnapshotHandler(
+		db,
+		"SELECT * FROM userinfo",
+		[]interface{}{},
+		sql.LevelDefault,
+	)
+	ds := New(h, []string{"uid", "username", "dept", "created"})
+
+	// Beginning the transaction blocks while the only database connection
+	// is in use, until ctx is done
+	dbConn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	_, err = ddataset.OpenContext(ctx, ds)
+	cancel()
+	if err != context.DeadlineExceeded {
+		t.Errorf("OpenContext - got err: %v, want: %s",
+			err, context.DeadlineExceeded)
+	}
+	if _, err := h.Rows(); err != ErrHandlerNotOpen {
+		t.Errorf("Rows - got err: %v, want: %s", err, ErrHandlerNotOpen)
+	}
+	dbConn.Close()
+
+	// Once begun the transaction outlives the context it was begun with
+	ctx, cancel = context.WithCancel(context.Background())
+	if err := h.OpenContext(ctx); err != nil {
+		t.Fatalf("OpenContext: %s", err)
+	}
+	cancel()
+	if got, err := h.Count(); err != nil || got != 5 {
+		t.Errorf("Count - got: %d, err: %v, want: 5", got, err)
+	}
+	h.Close()
+	if err := h.End(); err != nil {
+		t.Errorf("End: %s", err)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+// Package dsqlite handles access to an SQLite3 database so that it can
+// be used as a dsql.DBHandler
+package dsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/internal"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Handler handles access to an SQLite3 database and implements
+// dsql.DBHandler
+type Handler struct {
+	filename  string
+	tableName string
+	query     string
+	args      []interface{}
+	options   Options
+	db        *sql.DB
+	openConn  int
+	mu        sync.Mutex
+}
+
+// Options configures a Handler
+type Options struct {
+	// ReadOnly opens the database in read-only mode
+	ReadOnly bool
+	// CacheMB is the size of the SQLite page cache in megabytes.  If it
+	// is less than 1 then SQLite's default cache size is used.
+	CacheMB int
+	// KeyColumn is the column used to order the rows returned by
+	// PageRows.  Its values must be unique and not null.
+	KeyColumn string
+}
+
+// NewTableHandler creates a Handler which returns every row of
+// tableName in the database stored in filename
+func NewTableHandler(filename, tableName string, options Options) *Handler {
+	return &Handler{
+		filename:  filename,
+		tableName: tableName,
+		query:     fmt.Sprintf("SELECT * FROM %s", internal.QuoteIdentifier(tableName)),
+		args:      []interface{}{},
+		options:   options,
+		db:        nil,
+		openConn:  0,
+	}
+}
+
+// NewQueryHandler creates a Handler which returns the rows of an
+// SQL SELECT query run with args as its bind arguments on the
+// database stored in filename
+func NewQueryHandler(
+	filename string,
+	query string,
+	args []interface{},
+	options Options,
+) *Handler {
+	return &Handler{
+		filename:  filename,
+		tableName: "",
+		query:     query,
+		args:      args,
+		options:   options,
+		db:        nil,
+		openConn:  0,
+	}
+}
+
+// Open opens the database
+func (h *Handler) Open() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn > 0 {
+		h.openConn++
+		return nil
+	}
+	if !fileExists(h.filename) {
+		return fmt.Errorf("database doesn't exist: %s", h.filename)
+	}
+	db, err := sql.Open("sqlite3", h.dsn())
+	if err != nil {
+		return err
+	}
+	if h.tableName != "" {
+		if err := checkTableExists(db, h.tableName); err != nil {
+			db.Close()
+			return err
+		}
+	}
+	h.db = db
+	h.openConn = 1
+	return nil
+}
+
+// Close closes the database once every call to Open has been matched
+// by a call to Close
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn >= 1 {
+		h.openConn--
+		if h.openConn == 0 {
+			err := h.db.Close()
+			h.db = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Rows returns the rows for the table or query
+func (h *Handler) Rows() (*sql.Rows, error) {
+	return h.RowsContext(context.Background())
+}
+
+// RowsContext returns the rows for the table or query using ctx
+func (h *Handler) RowsContext(ctx context.Context) (*sql.Rows, error) {
+	h.mu.Lock()
+	db := h.db
+	h.mu.Unlock()
+	if db == nil {
+		return nil, fmt.Errorf("database isn't open: %s", h.filename)
+	}
+	return db.QueryContext(ctx, h.query, h.args...)
+}
+
+// PushdownRows returns the rows for the table or query using ctx with as
+// much of p applied as possible.  It returns a copy of p with the parts
+// that were applied cleared.
+func (h *Handler) PushdownRows(
+	ctx context.Context,
+	p ddataset.Pushdown,
+) (*sql.Rows, ddataset.Pushdown, error) {
+	h.mu.Lock()
+	db := h.db
+	h.mu.Unlock()
+	if db == nil {
+		return nil, p, fmt.Errorf("database isn't open: %s", h.filename)
+	}
+	query, remainder := internal.PushdownQuery(h.query, p)
+	rows, err := db.QueryContext(ctx, query, h.args...)
+	return rows, remainder, err
+}
+
+// Count returns the number of rows returned by the table or query
+// using SELECT COUNT(*)
+func (h *Handler) Count() (int64, error) {
+	h.mu.Lock()
+	db := h.db
+	h.mu.Unlock()
+	if db == nil {
+		return 0, fmt.Errorf("database isn't open: %s", h.filename)
+	}
+	var numRows int64
+	err := db.QueryRow(internal.CountQuery(h.query), h.args...).Scan(&numRows)
+	return numRows, err
+}
+
+// KeyColumn returns the name of the column that pages are ordered by,
+// as set in Options.KeyColumn
+func (h *Handler) KeyColumn() string {
+	return h.options.KeyColumn
+}
+
+// PageRows returns up to pageSize rows of the table or query, ordered by
+// Options.KeyColumn, whose key is greater than after.  If after is nil
+// then the rows start from the first row.
+func (h *Handler) PageRows(
+	ctx context.Context,
+	after interface{},
+	pageSize int,
+) (*sql.Rows, error) {
+	h.mu.Lock()
+	db := h.db
+	h.mu.Unlock()
+	if db == nil {
+		return nil, fmt.Errorf("database isn't open: %s", h.filename)
+	}
+	if h.options.KeyColumn == "" {
+		return nil, errors.New("no key column set")
+	}
+	args := h.args
+	afterPlaceholder := ""
+	if after != nil {
+		args = append(append([]interface{}{}, h.args...), after)
+		afterPlaceholder = "?"
+	}
+	query := internal.PageQuery(
+		h.query,
+		h.options.KeyColumn,
+		afterPlaceholder,
+		pageSize,
+	)
+	return db.QueryContext(ctx, query, args...)
+}
+
+// Columns returns the names of the columns returned by the table
+// or query.  These can be used as the field names for dsql.New.
+func (h *Handler) Columns() ([]string, error) {
+	if err := h.Open(); err != nil {
+		return nil, err
+	}
+	defer h.Close()
+	rows, err := h.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// dsn returns the data source name used to open the database
+func (h *Handler) dsn() string {
+	params := url.Values{}
+	if h.options.ReadOnly {
+		params.Set("mode", "ro")
+	}
+	if h.options.CacheMB > 0 {
+		params.Set("_cache_size", fmt.Sprintf("-%d000", h.options.CacheMB))
+	}
+	// The filename is escaped so that characters such as ? and # in it
+	// aren't taken as part of the URI's query
+	u := url.URL{
+		Scheme:   "file",
+		Opaque:   (&url.URL{Path: h.filename}).EscapedPath(),
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+// checkTableExists returns error if table doesn't exist in database
+func checkTableExists(db *sql.DB, tableName string) error {
+	var rowTableName string
+	tableNames := make([]string, 0)
+
+	rows, err := db.Query("select name from sqlite_master where type='table'")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Scan(&rowTableName); err != nil {
+			return err
+		}
+		tableNames = append(tableNames, rowTableName)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !inStringsSlice(tableName, tableNames) {
+		return fmt.Errorf("table name doesn't exist: %s", tableName)
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return fi.Mode().IsRegular()
+}
+
+func inStringsSlice(needle string, haystack []string) bool {
+	for _, v := range haystack {
+		if v == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package dsqlite
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/lawrencewoodman/ddataset"
+	"github.com/lawrencewoodman/ddataset/dsql"
+	"github.com/lawrencewoodman/ddataset/internal/testhelpers"
+	"github.com/lawrencewoodman/dlit"
+)
+
+func TestColumns(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	cases := []struct {
+		handler *Handler
+		want    []string
+	}{
+		{handler: NewTableHandler(filename, "userinfo", Options{}),
+			want: []string{"uid", "username", "dept", "started"},
+		},
+		{handler: NewQueryHandler(
+			filename,
+			"SELECT username AS name, dept FROM userinfo WHERE uid > ?",
+			[]interface{}{2},
+			Options{ReadOnly: true},
+		),
+			want: []string{"name", "dept"},
+		},
+	}
+	for i, c := range cases {
+		got, err := c.handler.Columns()
+		if err != nil {
+			t.Fatalf("(%d) Columns: %s", i, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("(%d) Columns - got: %s, want: %s", i, got, c.want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	cases := []struct {
+		handler     *Handler
+		wantRecords []ddataset.Record
+	}{
+		{handler: NewTableHandler(
+			filename,
+			"userinfo",
+			Options{ReadOnly: true, CacheMB: 64},
+		),
+			wantRecords: []ddataset.Record{
+				ddataset.Record{
+					"uid":      dlit.MustNew(1),
+					"username": dlit.MustNew("Fred Wilkins"),
+					"dept":     dlit.MustNew("Logistics"),
+					"started":  dlit.MustNew("2013-10-05 10:00:00"),
+				},
+				ddataset.Record{
+					"uid":      dlit.MustNew(2),
+					"username": dlit.MustNew("Bob Field"),
+					"dept":     dlit.MustNew("Logistics"),
+					"started":  dlit.MustNew("2013-05-05 10:00:00"),
+				},
+				ddataset.Record{
+					"uid":      dlit.MustNew(3),
+					"username": dlit.MustNew("Ned James"),
+					"dept":     dlit.MustNew("Shipping"),
+					"started":  dlit.MustNew("2012-05-05 10:00:00"),
+				},
+				ddataset.Record{
+					"uid":      dlit.MustNew(4),
+					"username": dlit.MustNew("Mary Terence"),
+					"dept":     dlit.MustNew("Shipping"),
+					"started":  dlit.MustNew("2011-05-05 10:00:00"),
+				},
+				ddataset.Record{
+					"uid":      dlit.MustNew(5),
+					"username": dlit.MustNew("George Eliot"),
+					"dept":     ddataset.NewNull(),
+					"started":  dlit.MustNew("2010-05-05 10:00:00"),
+				},
+			},
+		},
+		{handler: NewQueryHandler(
+			filename,
+			"SELECT username AS name, dept FROM userinfo "+
+				"WHERE uid > ? AND dept = ? ORDER BY uid",
+			[]interface{}{2, "Shipping"},
+			Options{},
+		),
+			wantRecords: []ddataset.Record{
+				ddataset.Record{
+					"name": dlit.MustNew("Ned James"),
+					"dept": dlit.MustNew("Shipping"),
+				},
+				ddataset.Record{
+					"name": dlit.MustNew("Mary Terence"),
+					"dept": dlit.MustNew("Shipping"),
+				},
+			},
+		},
+	}
+	for i, c := range cases {
+		fieldNames, err := c.handler.Columns()
+		if err != nil {
+			t.Fatalf("(%d) Columns: %s", i, err)
+		}
+		ds := dsql.New(c.handler, fieldNames)
+		conn, err := ds.Open()
+		if err != nil {
+			t.Fatalf("(%d) Open: %s", i, err)
+		}
+		for _, wantRecord := range c.wantRecords {
+			if !conn.Next() {
+				t.Fatalf("(%d) Next() - return false early", i)
+			}
+			record := conn.Read()
+			if !testhelpers.MatchRecords(record, wantRecord) {
+				t.Errorf("(%d) Read() got: %s, want: %s", i, record, wantRecord)
+			}
+		}
+		if conn.Next() {
+			t.Errorf("(%d) Next() - return true, despite having finished", i)
+		}
+		if err := conn.Err(); err != nil {
+			t.Errorf("(%d) Err() err: %s", i, err)
+		}
+		conn.Close()
+	}
+}
+
+func TestCount(t *testing.T) {
+	filename := filepath.Join("fixtures", "users.db")
+	cases := []struct {
+		handler *Handler
+		want    int64
+	}{
+		{handler: NewTableHandler(filename, "userinfo", Options{}),
+			want: 5,
+		},
+		{handler: NewQueryHandler(
+			filename,
+			"SELECT * FROM userinfo WHERE uid > ?",
+			[]interface{}{3},
+			Options{ReadOnly: true},
+		),
+			want: 2,
+		},
+	}
+	for i, c := range cases {
+		if err := c.handler.Open(); err != nil {
+			t.Fatalf("(%d) Open: %s", i, err)
+		}
+		got, err := c.handler.Count()
+		if err != nil {
+			t.Errorf("(%d) Count: %s", i, err)
+		}
+		if got != c.want {
+			t.Errorf("(%d) Count - got: %d, want: %d", i, got, c.want)
+		}
+		c.handler.Close()
+	}
+}
+
+func TestOpen_errors(t *testing.T) {
+	cases := []struct {
+		handler *Handler
+		wantErr error
+	}{
+		{handler: NewTableHandler(
+			filepath.Join("fixtures", "missing.db"),
+			"userinfo",
+			Options{},
+		),
+			wantErr: fmt.Errorf("database doesn't exist: %s",
+				filepath.Join("fixtures", "missing.db")),
+		},
+		{handler: NewTableHandler(
+			filepath.Join("fixtures", "users.db"),
+			"missing",
+			Options{ReadOnly: true},
+		),
+			wantErr: errors.New("table name doesn't exist: missing"),
+		},
+	}
+	for i, c := range cases {
+		if err := c.handler.Open(); !testhelpers.ErrorMatch(err, c.wantErr) {
+			t.Errorf("(%d) Open - got err: %v, want: %s", i, err, c.wantErr)
+		}
+	}
+}
+
+func TestOpen_readOnly(t *testing.T) {
+	h := NewTableHandler(
+		filepath.Join("fixtures", "users.db"),
+		"userinfo",
+		Options{ReadOnly: true},
+	)
+	if err := h.Open(); err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer h.Close()
+	_, err := h.db.Exec("DELETE FROM userinfo")
+	if err == nil {
+		t.Errorf("Exec - expected error writing to read-only database")
+	}
+}
+
+func TestOpen_specialFilename(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "dsqlite")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	src, err := ioutil.ReadFile(filepath.Join("fixtures", "users.db"))
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	cases := []struct {
+		name    string
+		options Options
+	}{
+		{name: "users?mode=rw.db", options: Options{}},
+		{name: "users?mode=rw.db", options: Options{ReadOnly: true}},
+		{name: "users#1.db", options: Options{}},
+		{name: "users#1.db", options: Options{ReadOnly: true, CacheMB: 4}},
+		{name: "users 100%.db", options: Options{}},
+		{name: "users 100%.db", options: Options{ReadOnly: true}},
+	}
+	for i, c := range cases {
+		filename := filepath.Join(tmpDir, c.name)
+		if err := ioutil.WriteFile(filename, src, 0644); err != nil {
+			t.Fatalf("(%d) WriteFile: %s", i, err)
+		}
+		h := NewTableHandler(filename, "userinfo", c.options)
+		if err := h.Open(); err != nil {
+			t.Errorf("(%d) Open: %s", i, err)
+			continue
+		}
+		if got, err := h.Count(); err != nil || got != 5 {
+			t.Errorf("(%d) Count - got: %d, err: %v, want: 5", i, got, err)
+		}
+		_, err := h.db.Exec("DELETE FROM userinfo")
+		if c.options.ReadOnly && err == nil {
+			t.Errorf("(%d) Exec - expected error writing to read-only database", i)
+		} else if !c.options.ReadOnly && err != nil {
+			t.Errorf("(%d) Exec: %s", i, err)
+		}
+		h.Close()
+	}
+}
+
+func TestRows_closed(t *testing.T) {
+	h := NewTableHandler(
+		filepath.Join("fixtures", "users.db"),
+		"userinfo",
+		Options{},
+	)
+	wantErr := fmt.Errorf("database isn't open: %s",
+		filepath.Join("fixtures", "users.db"))
+	if _, err := h.Rows(); !testhelpers.ErrorMatch(err, wantErr) {
+		t.Errorf("Rows - got err: %v, want: %s", err, wantErr)
+	}
+}
+
+func TestOpenClose_goroutines(t *testing.T) {
+	var numGoroutines int
+	if testing.Short() {
+		numGoroutines = 10
+	} else {
+		numGoroutines = 100
+	}
+	h := NewTableHandler(
+		filepath.Join("fixtures", "users.db"),
+		"userinfo",
+		Options{ReadOnly: true},
+	)
+	wg := sync.WaitGroup{}
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			if err := h.Open(); err != nil {
+				t.Errorf("Open: %s", err)
+				return
+			}
+			defer h.Close()
+			rows, err := h.Rows()
+			if err != nil {
+				t.Errorf("Rows: %s", err)
+				return
+			}
+			defer rows.Close()
+			numRows := 0
+			for rows.Next() {
+				numRows++
+			}
+			if err := rows.Err(); err != nil {
+				t.Errorf("rows.Err: %s", err)
+			}
+			if numRows != 5 {
+				t.Errorf("numRows - got: %d, want: 5", numRows)
+			}
+		}()
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openConn != 0 || h.db != nil {
+		t.Errorf("handler still open - openConn: %d, db: %v", h.openConn, h.db)
+	}
+}
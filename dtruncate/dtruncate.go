@@ -10,6 +10,8 @@
 package dtruncate
 
 import (
+	"context"
+
 	"github.com/lawrencewoodman/ddataset"
 	"github.com/lawrencewoodman/ddataset/internal"
 )
@@ -23,10 +25,11 @@ type DTruncate struct {
 
 // DTruncateConn represents a connection to a DTruncate Dataset
 type DTruncateConn struct {
-	dataset   *DTruncate
-	conn      ddataset.Conn
-	recordNum int64
-	err       error
+	dataset    *DTruncate
+	conn       ddataset.Conn
+	numRecords int64
+	recordNum  int64
+	err        error
 }
 
 // New creates a new DTruncate Dataset
@@ -40,19 +43,47 @@ func New(dataset ddataset.Dataset, numRecords int64) ddataset.Dataset {
 
 // Open creates a connection to the Dataset
 func (d *DTruncate) Open() (ddataset.Conn, error) {
+	return d.OpenContext(context.Background())
+}
+
+// OpenContext creates a connection to the Dataset which stops returning
+// Records once ctx is done.  The limit on the number of records is pushed
+// down to the underlying Dataset if it implements ddataset.PushdownOpener.
+func (d *DTruncate) OpenContext(ctx context.Context) (ddataset.Conn, error) {
+	conn, _, err := d.OpenPushdown(ctx, ddataset.Pushdown{})
+	return conn, err
+}
+
+// OpenPushdown is like OpenContext but also applies p.  A limit in p is
+// combined with the number of records the Dataset is truncated to and
+// is always applied.  The rest of p is passed to the underlying Dataset.
+func (d *DTruncate) OpenPushdown(
+	ctx context.Context,
+	p ddataset.Pushdown,
+) (ddataset.Conn, ddataset.Pushdown, error) {
 	if d.isReleased {
-		return nil, ddataset.ErrReleased
+		return nil, p, ddataset.ErrReleased
 	}
-	conn, err := d.dataset.Open()
+	numRecords := d.numRecords
+	if p.HasLimit && p.Limit < numRecords {
+		numRecords = p.Limit
+	}
+	down := p
+	down.HasLimit = true
+	down.Limit = numRecords
+	conn, remainder, err := ddataset.OpenPushdown(ctx, d.dataset, down)
 	if err != nil {
-		return nil, err
+		return nil, p, err
 	}
+	remainder.HasLimit = false
+	remainder.Limit = 0
 	return &DTruncateConn{
-		dataset:   d,
-		conn:      conn,
-		recordNum: 0,
-		err:       nil,
-	}, nil
+		dataset:    d,
+		conn:       conn,
+		numRecords: numRecords,
+		recordNum:  0,
+		err:        nil,
+	}, remainder, nil
 }
 
 // Fields returns the field names used by the Dataset
@@ -60,6 +91,14 @@ func (d *DTruncate) Fields() []string {
 	return d.dataset.Fields()
 }
 
+// Schema returns the Schema of the underlying Dataset
+func (d *DTruncate) Schema() ddataset.Schema {
+	if d.isReleased {
+		return ddataset.Schema{}
+	}
+	return ddataset.SchemaOf(d.dataset)
+}
+
 // NumRecords returns the number of records in the Dataset.  If there is
 // a problem getting the number of records it returns -1.  NOTE: The returned
 // value can change if the underlying Dataset changes.
@@ -82,7 +121,7 @@ func (c *DTruncateConn) Next() bool {
 	if c.conn.Err() != nil {
 		return false
 	}
-	if c.recordNum < c.dataset.numRecords {
+	if c.recordNum < c.numRecords {
 		c.recordNum++
 		return c.conn.Next()
 	}
@@ -1,6 +1,7 @@
 package dtruncate
 
 import (
+	"context"
 	"encoding/csv"
 	"errors"
 	"github.com/lawrencewoodman/ddataset"
@@ -66,6 +67,30 @@ func TestFields(t *testing.T) {
 	}
 }
 
+func TestSchema(t *testing.T) {
+	filename := filepath.Join("fixtures", "bank.csv")
+	schema := ddataset.NewSchema([]string{
+		"age", "job", "marital", "education", "default", "balance",
+		"housing", "loan", "contact", "day", "month", "duration", "campaign",
+		"pdays", "previous", "poutcome", "y",
+	})
+	schema[0].Kind = ddataset.KindInt
+	schema[5].Kind = ddataset.KindInt
+	schema[5].Nullable = true
+	ds := dcsv.NewWithSchema(filename, true, ';', schema)
+	rds := New(ds, 10)
+	if got := ddataset.SchemaOf(rds); !reflect.DeepEqual(got, schema) {
+		t.Errorf("Schema() - got: %v, want: %v", got, schema)
+	}
+	if err := rds.Release(); err != nil {
+		t.Fatalf("Release: %s", err)
+	}
+	got := rds.(ddataset.SchemaDataset).Schema()
+	if want := (ddataset.Schema{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Schema() - got: %v, want: %v", got, want)
+	}
+}
+
 func TestNumRecords(t *testing.T) {
 	cases := []struct {
 		filename           string
@@ -278,17 +303,27 @@ func TestErr(t *testing.T) {
 		{filepath.Join("fixtures", "invalid_numfields_at_102.csv"), ',',
 			[]string{"band", "score", "team", "points", "rating"},
 			105,
-			&csv.ParseError{
-				Line:   102,
-				Column: 0,
-				Err:    csv.ErrFieldCount,
+			&ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "invalid_numfields_at_102.csv"),
+				Record:    102,
+				Line:      102,
+				NumFields: 5,
+				NumValues: 4,
+				Err:       csv.ErrFieldCount,
 			}},
 		{filepath.Join("fixtures", "bank.csv"), ';',
 			[]string{"age", "job", "marital", "education", "default", "balance",
 				"housing", "loan", "contact", "day", "month", "duration", "campaign",
 				"pdays", "previous", "poutcome"},
 			4,
-			errors.New("wrong number of field names for dataset")},
+			&ddataset.RecordError{
+				Source:    filepath.Join("fixtures", "bank.csv"),
+				Record:    1,
+				Line:      1,
+				NumFields: 16,
+				NumValues: 17,
+				Err:       ddataset.ErrWrongNumFields,
+			}},
 		{filepath.Join("fixtures", "bank.csv"), ';',
 			[]string{"age", "job", "marital", "education", "default", "balance",
 				"housing", "loan", "contact", "day", "month", "duration", "campaign",
@@ -404,3 +439,87 @@ func TestNext_errors(t *testing.T) {
 		}
 	}
 }
+
+func TestOpenContext_cancel(t *testing.T) {
+	filename := filepath.Join("fixtures", "bank.csv")
+	fieldNames := []string{"age", "job", "marital", "education", "default", "balance",
+		"housing", "loan", "contact", "day", "month", "duration", "campaign",
+		"pdays", "previous", "poutcome", "y"}
+	ds := dcsv.New(filename, true, ';', fieldNames)
+	rds := New(ds, 8)
+	if err := testhelpers.CheckOpenContextCancel(rds, 3); err != nil {
+		t.Errorf("CheckOpenContextCancel: %s", err)
+	}
+}
+
+func TestOpenPushdown(t *testing.T) {
+	filename := filepath.Join("fixtures", "bank.csv")
+	fieldNames := []string{"age", "job", "marital", "education", "default",
+		"balance", "housing", "loan", "contact", "day", "month", "duration",
+		"campaign", "pdays", "previous", "poutcome", "y"}
+	cases := []struct {
+		numRecords     int64
+		pushdown       ddataset.Pushdown
+		wantPushdown   ddataset.Pushdown
+		wantNumRecords int64
+	}{
+		{numRecords: 5,
+			pushdown:       ddataset.Pushdown{},
+			wantPushdown:   ddataset.Pushdown{HasLimit: true, Limit: 5},
+			wantNumRecords: 5,
+		},
+		{numRecords: 5,
+			pushdown:       ddataset.Pushdown{HasLimit: true, Limit: 3},
+			wantPushdown:   ddataset.Pushdown{HasLimit: true, Limit: 3},
+			wantNumRecords: 3,
+		},
+		{numRecords: 5,
+			pushdown:       ddataset.Pushdown{HasLimit: true, Limit: 7},
+			wantPushdown:   ddataset.Pushdown{HasLimit: true, Limit: 5},
+			wantNumRecords: 5,
+		},
+	}
+	for i, c := range cases {
+		ds := &pushdownDataset{
+			Dataset: dcsv.New(filename, true, ';', fieldNames),
+		}
+		rds := New(ds, c.numRecords).(*DTruncate)
+		conn, remainder, err := rds.OpenPushdown(context.Background(), c.pushdown)
+		if err != nil {
+			t.Fatalf("(%d) OpenPushdown: %s", i, err)
+		}
+		if ds.pushdown != c.wantPushdown {
+			t.Errorf("(%d) OpenPushdown - got pushed down: %v, want: %v",
+				i, ds.pushdown, c.wantPushdown)
+		}
+		if remainder != (ddataset.Pushdown{}) {
+			t.Errorf("(%d) OpenPushdown - got remainder: %v, want: none",
+				i, remainder)
+		}
+		numRecords := int64(0)
+		for conn.Next() {
+			numRecords++
+		}
+		if numRecords != c.wantNumRecords {
+			t.Errorf("(%d) Next - got records: %d, want: %d",
+				i, numRecords, c.wantNumRecords)
+		}
+		conn.Close()
+	}
+}
+
+// pushdownDataset records the Pushdown it is opened with but doesn't
+// apply it
+type pushdownDataset struct {
+	ddataset.Dataset
+	pushdown ddataset.Pushdown
+}
+
+func (d *pushdownDataset) OpenPushdown(
+	ctx context.Context,
+	p ddataset.Pushdown,
+) (ddataset.Conn, ddataset.Pushdown, error) {
+	d.pushdown = p
+	conn, err := ddataset.OpenContext(ctx, d.Dataset)
+	return conn, p, err
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lawrencewoodman/ddataset"
+)
+
+// CountQuery returns a query which counts the rows returned by query
+func CountQuery(query string) string {
+	return fmt.Sprintf(
+		"SELECT COUNT(*) FROM (%s) AS ddataset_count",
+		trimQuery(query),
+	)
+}
+
+// PageQuery returns a query which returns up to pageSize rows of query
+// ordered by keyColumn.  If afterPlaceholder isn't empty then only rows
+// whose key is greater than the value bound to it are returned.
+func PageQuery(
+	query string,
+	keyColumn string,
+	afterPlaceholder string,
+	pageSize int,
+) string {
+	where := ""
+	if afterPlaceholder != "" {
+		where = fmt.Sprintf(
+			" WHERE %s > %s",
+			QuoteIdentifier(keyColumn), afterPlaceholder,
+		)
+	}
+	return fmt.Sprintf(
+		"SELECT * FROM (%s) AS ddataset_page%s ORDER BY %s LIMIT %d",
+		trimQuery(query), where, QuoteIdentifier(keyColumn), pageSize,
+	)
+}
+
+// PushdownQuery returns query with as much of p applied as possible and
+// a copy of p with the parts that were applied cleared
+func PushdownQuery(
+	query string,
+	p ddataset.Pushdown,
+) (string, ddataset.Pushdown) {
+	if !p.HasLimit {
+		return query, p
+	}
+	limit := p.Limit
+	if limit < 0 {
+		limit = 0
+	}
+	p.HasLimit = false
+	p.Limit = 0
+	return fmt.Sprintf(
+		"SELECT * FROM (%s) AS ddataset_limit LIMIT %d",
+		trimQuery(query), limit,
+	), p
+}
+
+// QuoteIdentifier returns name quoted so that it can be used as an
+// SQL identifier
+func QuoteIdentifier(name string) string {
+	return "\"" + strings.Replace(name, "\"", "\"\"", -1) + "\""
+}
+
+// trimQuery returns query without surrounding space or a trailing
+// semicolon so that it can be used as a subquery
+func trimQuery(query string) string {
+	return strings.TrimRight(strings.TrimSpace(query), ";")
+}
@@ -0,0 +1,28 @@
+package internal
+
+import "testing"
+
+func TestPageQuery(t *testing.T) {
+	cases := []struct {
+		query            string
+		afterPlaceholder string
+		want             string
+	}{
+		{query: "SELECT * FROM userinfo",
+			afterPlaceholder: "",
+			want: "SELECT * FROM (SELECT * FROM userinfo) AS ddataset_page " +
+				"ORDER BY \"uid\" LIMIT 100",
+		},
+		{query: "SELECT * FROM userinfo WHERE dept = $1;",
+			afterPlaceholder: "$2",
+			want: "SELECT * FROM (SELECT * FROM userinfo WHERE dept = $1) " +
+				"AS ddataset_page WHERE \"uid\" > $2 ORDER BY \"uid\" LIMIT 100",
+		},
+	}
+	for i, c := range cases {
+		got := PageQuery(c.query, "uid", c.afterPlaceholder, 100)
+		if got != c.want {
+			t.Errorf("(%d) pageQuery - got: %s, want: %s", i, got, c.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package testhelpers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/lawrencewoodman/ddataset"
@@ -48,13 +49,46 @@ func CheckDatasetConnsEqual(c1, c2 ddataset.Conn) error {
 	return nil
 }
 
+// CheckOpenContextCancel returns an error if a connection opened with
+// ddataset.OpenContext doesn't stop once its context has been cancelled
+// after cancelRow records have been read
+func CheckOpenContextCancel(ds ddataset.Dataset, cancelRow int) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := ddataset.OpenContext(ctx, ds)
+	if err != nil {
+		return fmt.Errorf("OpenContext: %s", err)
+	}
+	defer conn.Close()
+	numRecords := 0
+	for conn.Next() {
+		numRecords++
+		if numRecords == cancelRow {
+			cancel()
+		}
+	}
+	if numRecords != cancelRow {
+		return fmt.Errorf("Next() - numRecords: %d, want: %d",
+			numRecords, cancelRow)
+	}
+	if conn.Err() != context.Canceled {
+		return fmt.Errorf("Err() - got: %v, want: %s",
+			conn.Err(), context.Canceled)
+	}
+	return nil
+}
+
 // MatchRecords returns whether two records are equal
 func MatchRecords(r1 ddataset.Record, r2 ddataset.Record) bool {
 	if len(r1) != len(r2) {
 		return false
 	}
 	for fieldName, value := range r1 {
-		if value.String() != r2[fieldName].String() {
+		v2, ok := r2[fieldName]
+		if !ok || ddataset.IsNull(value) != ddataset.IsNull(v2) {
+			return false
+		}
+		if !ddataset.IsNull(value) && value.String() != v2.String() {
 			return false
 		}
 	}
@@ -0,0 +1,25 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package internal
+
+import "time"
+
+// timeLayouts are the layouts tried by ParseTime
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseTime parses s as a date or time using RFC 3339 or the layouts
+// 2006-01-02 15:04:05 and 2006-01-02, which are taken to be in UTC.  It
+// returns false if s doesn't match any of them.
+func ParseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,46 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package ddataset
+
+import "context"
+
+// Pushdown describes operations which a Dataset wrapping another Dataset
+// would like the wrapped Dataset to apply natively, such as by adding a
+// LIMIT clause to an SQL query.  The zero value asks for nothing.
+type Pushdown struct {
+	// HasLimit indicates that at most Limit records are wanted
+	HasLimit bool
+	// Limit is the maximum number of records wanted if HasLimit is set
+	Limit int64
+}
+
+// PushdownOpener is implemented by Datasets which can apply some or all
+// of a Pushdown natively when creating a connection
+type PushdownOpener interface {
+	// OpenPushdown is like OpenContext but the connection has as much of p
+	// applied as the Dataset can manage.  It returns a copy of p with the
+	// parts that were applied cleared, so that the caller can apply the
+	// rest itself.  Any parts of p that the Dataset doesn't know about
+	// must be returned unchanged.
+	OpenPushdown(ctx context.Context, p Pushdown) (Conn, Pushdown, error)
+}
+
+// OpenPushdown creates a connection to Dataset d which is bound to ctx and
+// asks d to apply p.  It returns the parts of p which weren't applied and
+// must be applied by the caller.  If d doesn't implement PushdownOpener
+// then OpenContext is used and p is returned unchanged.
+func OpenPushdown(
+	ctx context.Context,
+	d Dataset,
+	p Pushdown,
+) (Conn, Pushdown, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, p, err
+	}
+	if po, ok := d.(PushdownOpener); ok {
+		return po.OpenPushdown(ctx, p)
+	}
+	conn, err := OpenContext(ctx, d)
+	return conn, p, err
+}
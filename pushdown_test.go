@@ -0,0 +1,44 @@
+package ddataset
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lawrencewoodman/dlit"
+)
+
+func TestOpenPushdown(t *testing.T) {
+	ds := &sliceDataset{
+		records: []Record{
+			Record{"n": dlit.MustNew(1)},
+			Record{"n": dlit.MustNew(2)},
+			Record{"n": dlit.MustNew(3)},
+		},
+	}
+	p := Pushdown{HasLimit: true, Limit: 1}
+	conn, remainder, err := OpenPushdown(context.Background(), ds, p)
+	if err != nil {
+		t.Fatalf("OpenPushdown: %s", err)
+	}
+	defer conn.Close()
+	if remainder != p {
+		t.Errorf("OpenPushdown - got remainder: %v, want: %v", remainder, p)
+	}
+	numRecords := 0
+	for conn.Next() {
+		numRecords++
+	}
+	if numRecords != 3 {
+		t.Errorf("Next - got records: %d, want: 3", numRecords)
+	}
+}
+
+func TestOpenPushdown_done(t *testing.T) {
+	ds := &sliceDataset{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := OpenPushdown(ctx, ds, Pushdown{})
+	if err != context.Canceled {
+		t.Errorf("OpenPushdown - got err: %v, want: %s", err, context.Canceled)
+	}
+}
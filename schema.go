@@ -0,0 +1,101 @@
+// Copyright (C) 2026 Lawrence Woodman <lwoodman@vlifesystems.com>
+// Licensed under an MIT licence.  Please see LICENCE.md for details.
+
+package ddataset
+
+// Kind represents the kind of values held by a field
+type Kind int
+
+const (
+	// KindUnknown indicates that the kind of a field isn't known
+	KindUnknown Kind = iota
+	// KindString indicates a field holds strings
+	KindString
+	// KindInt indicates a field holds integers
+	KindInt
+	// KindFloat indicates a field holds floating point numbers
+	KindFloat
+	// KindBool indicates a field holds booleans
+	KindBool
+	// KindTime indicates a field holds dates or times
+	KindTime
+)
+
+// String returns the name of the Kind
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindInt:
+		return "int"
+	case KindFloat:
+		return "float"
+	case KindBool:
+		return "bool"
+	case KindTime:
+		return "time"
+	}
+	return "unknown"
+}
+
+// Field describes a single field of a Dataset
+type Field struct {
+	// Name is the name of the field as returned by Dataset.Fields
+	Name string
+	// Kind is the kind of values held by the field
+	Kind Kind
+	// Nullable is whether the field can hold null values
+	Nullable bool
+	// Description is an optional description of the field
+	Description string
+}
+
+// Schema describes the fields of a Dataset in the same order as
+// returned by Dataset.Fields
+type Schema []Field
+
+// SchemaDataset is implemented by Datasets which can describe their
+// fields with a Schema
+type SchemaDataset interface {
+	// Schema returns the Schema of the Dataset
+	Schema() Schema
+}
+
+// NewSchema creates a Schema for fieldNames where the Kind of each
+// field is KindUnknown
+func NewSchema(fieldNames []string) Schema {
+	s := make(Schema, len(fieldNames))
+	for i, name := range fieldNames {
+		s[i] = Field{Name: name, Kind: KindUnknown}
+	}
+	return s
+}
+
+// SchemaOf returns the Schema of Dataset d.  If d doesn't implement
+// SchemaDataset then a Schema is created from d.Fields with each field
+// having KindUnknown.
+func SchemaOf(d Dataset) Schema {
+	if sd, ok := d.(SchemaDataset); ok {
+		return sd.Schema()
+	}
+	return NewSchema(d.Fields())
+}
+
+// Names returns the field names of the Schema
+func (s Schema) Names() []string {
+	names := make([]string, len(s))
+	for i, f := range s {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Field returns the Field with name and whether it was found
+func (s Schema) Field(name string) (Field, bool) {
+	for _, f := range s {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
@@ -0,0 +1,78 @@
+package ddataset
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKindString(t *testing.T) {
+	cases := []struct {
+		kind Kind
+		want string
+	}{
+		{KindUnknown, "unknown"},
+		{KindString, "string"},
+		{KindInt, "int"},
+		{KindFloat, "float"},
+		{KindBool, "bool"},
+		{KindTime, "time"},
+		{Kind(99), "unknown"},
+	}
+	for _, c := range cases {
+		if got := c.kind.String(); got != c.want {
+			t.Errorf("String() - got: %s, want: %s", got, c.want)
+		}
+	}
+}
+
+func TestSchemaNames(t *testing.T) {
+	schema := Schema{
+		{Name: "name", Kind: KindString},
+		{Name: "balance", Kind: KindInt},
+		{Name: "tertiary_educated", Kind: KindBool, Nullable: true},
+	}
+	want := []string{"name", "balance", "tertiary_educated"}
+	if got := schema.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() - got: %s, want: %s", got, want)
+	}
+}
+
+func TestSchemaField(t *testing.T) {
+	schema := Schema{
+		{Name: "name", Kind: KindString},
+		{Name: "balance", Kind: KindInt, Description: "Balance in pence"},
+	}
+	got, ok := schema.Field("balance")
+	if !ok || !reflect.DeepEqual(got, schema[1]) {
+		t.Errorf("Field(\"balance\") - got: %v, %t, want: %v, true",
+			got, ok, schema[1])
+	}
+	if _, ok := schema.Field("missing"); ok {
+		t.Errorf("Field(\"missing\") - got: true, want: false")
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	ds := &sliceDataset{}
+	want := Schema{{Name: "n", Kind: KindUnknown}}
+	if got := SchemaOf(ds); !reflect.DeepEqual(got, want) {
+		t.Errorf("SchemaOf() - got: %v, want: %v", got, want)
+	}
+
+	sds := &schemaDataset{
+		sliceDataset: ds,
+		schema:       Schema{{Name: "n", Kind: KindInt, Nullable: true}},
+	}
+	if got := SchemaOf(sds); !reflect.DeepEqual(got, sds.schema) {
+		t.Errorf("SchemaOf() - got: %v, want: %v", got, sds.schema)
+	}
+}
+
+type schemaDataset struct {
+	*sliceDataset
+	schema Schema
+}
+
+func (d *schemaDataset) Schema() Schema {
+	return d.schema
+}